@@ -0,0 +1,427 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goSource is a parsed Go file edited in place by generators.
+//
+// Nodes are located with go/ast, the change is spliced into the original
+// source at the node offsets and the result is run through go/format. Comments,
+// field alignment and hand-written layout survive, and every edit checks the
+// AST first so running a generator twice is a no-op.
+type goSource struct {
+	path string
+	src  []byte
+	fset *token.FileSet
+	file *ast.File
+}
+
+// loadGoSource reads and parses the Go file at path
+func loadGoSource(path string) (*goSource, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &goSource{path: path}
+	if err := s.reset(src); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reset formats src and parses it as the current file contents
+func (s *goSource) reset(src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("cannot format %s: %w", s.path, err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, s.path, formatted, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", s.path, err)
+	}
+	s.src, s.fset, s.file = formatted, fset, file
+	return nil
+}
+
+// save writes the current contents back to disk
+func (s *goSource) save() error {
+	return os.WriteFile(s.path, s.src, 0644)
+}
+
+func (s *goSource) offset(pos token.Pos) int {
+	return s.fset.Position(pos).Offset
+}
+
+// text returns the source text of a node
+func (s *goSource) text(n ast.Node) string {
+	return string(s.src[s.offset(n.Pos()):s.offset(n.End())])
+}
+
+// splice replaces src[from:to] with text and re-parses the file
+func (s *goSource) splice(from, to int, text string) error {
+	var buf bytes.Buffer
+	buf.Write(s.src[:from])
+	buf.WriteString(text)
+	buf.Write(s.src[to:])
+	return s.reset(buf.Bytes())
+}
+
+// lineEnd returns the offset of the newline ending the line that contains off
+func (s *goSource) lineEnd(off int) int {
+	if i := bytes.IndexByte(s.src[off:], '\n'); i >= 0 {
+		return off + i
+	}
+	return len(s.src)
+}
+
+// lineStart returns the offset of the first byte of the line that contains off
+func (s *goSource) lineStart(off int) int {
+	return bytes.LastIndexByte(s.src[:off], '\n') + 1
+}
+
+// compact strips whitespace so expressions can be compared regardless of formatting
+func compact(str string) string {
+	return strings.Join(strings.Fields(str), "")
+}
+
+// providerSet finds the wire.NewSet(...) call assigned to the package level variable name
+func (s *goSource) providerSet(name string) (*ast.CallExpr, error) {
+	for _, decl := range s.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, ident := range vs.Names {
+				if ident.Name != name || i >= len(vs.Values) {
+					continue
+				}
+				call, ok := vs.Values[i].(*ast.CallExpr)
+				if !ok {
+					return nil, fmt.Errorf("%s in %s is not a wire.NewSet call", name, s.path)
+				}
+				if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "NewSet" {
+					return nil, fmt.Errorf("%s in %s is not a wire.NewSet call", name, s.path)
+				}
+				return call, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s not found in %s", name, s.path)
+}
+
+// addProvider appends expr to the wire.NewSet call of the variable setName
+func (s *goSource) addProvider(setName, expr string) (bool, error) {
+	call, err := s.providerSet(setName)
+	if err != nil {
+		return false, err
+	}
+	for _, arg := range call.Args {
+		if compact(s.text(arg)) == compact(expr) {
+			return false, nil
+		}
+	}
+	if len(call.Args) == 0 {
+		rparen := s.offset(call.Rparen)
+		return true, s.splice(rparen, rparen, "\n\t"+expr+",\n")
+	}
+	last := s.offset(call.Args[len(call.Args)-1].End())
+	return true, s.splice(last, last, ",\n\t"+expr)
+}
+
+// structType finds the struct type declared as name
+func (s *goSource) structType(name string) (*ast.StructType, error) {
+	for _, decl := range s.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if ts.Name.Name != name {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				return nil, fmt.Errorf("%s in %s is not a struct", name, s.path)
+			}
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("type %s not found in %s", name, s.path)
+}
+
+// structField returns the field called fieldName, or nil
+func structField(st *ast.StructType, fieldName string) *ast.Field {
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			if ident.Name == fieldName {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// addStructField adds "fieldName typeExpr" as the last field of struct structName
+func (s *goSource) addStructField(structName, fieldName, typeExpr string) (bool, error) {
+	st, err := s.structType(structName)
+	if err != nil {
+		return false, err
+	}
+	if field := structField(st, fieldName); field != nil {
+		if compact(s.text(field.Type)) != compact(typeExpr) {
+			return false, fmt.Errorf("%s.%s already exists with type %s", structName, fieldName, s.text(field.Type))
+		}
+		return false, nil
+	}
+	line := fieldName + " " + typeExpr
+	if len(st.Fields.List) == 0 {
+		opening, closing := s.offset(st.Fields.Opening), s.offset(st.Fields.Closing)
+		return true, s.splice(opening+1, closing, "\n\t"+line+"\n")
+	}
+	// insert after the line of the last field so its trailing comment stays put
+	at := s.lineEnd(s.offset(st.Fields.List[len(st.Fields.List)-1].End()))
+	return true, s.splice(at, at, "\n\t"+line)
+}
+
+// hasImport reports whether path is imported
+func (s *goSource) hasImport(path string) bool {
+	for _, imp := range s.file.Imports {
+		if p, _ := strconv.Unquote(imp.Path.Value); p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// addImport imports path, adding it to the first import block
+func (s *goSource) addImport(path string) (bool, error) {
+	if s.hasImport(path) {
+		return false, nil
+	}
+	spec := strconv.Quote(path)
+	for _, decl := range s.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			lparen := s.offset(gen.Lparen)
+			return true, s.splice(lparen+1, lparen+1, "\n\t"+spec)
+		}
+		// single import without parentheses: turn it into a block
+		from, to := s.offset(gen.Pos()), s.offset(gen.End())
+		return true, s.splice(from, to, "import (\n\t"+spec+"\n\t"+s.text(gen.Specs[0])+"\n)")
+	}
+	at := s.offset(s.file.Name.End())
+	return true, s.splice(at, at, "\n\nimport "+spec)
+}
+
+// funcDecl finds the top level function called name
+func (s *goSource) funcDecl(name string) (*ast.FuncDecl, error) {
+	for _, decl := range s.file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name && fn.Body != nil {
+			return fn, nil
+		}
+	}
+	return nil, fmt.Errorf("func %s not found in %s", name, s.path)
+}
+
+// addParam appends the parameter "name typeExpr" to function funcName unless
+// a parameter of that type already exists
+func (s *goSource) addParam(funcName, name, typeExpr string) (bool, error) {
+	fn, err := s.funcDecl(funcName)
+	if err != nil {
+		return false, err
+	}
+	params := fn.Type.Params
+	for _, field := range params.List {
+		if compact(s.text(field.Type)) == compact(typeExpr) {
+			return false, nil
+		}
+	}
+	param := name + " " + typeExpr
+	if len(params.List) > 0 {
+		param = ", " + param
+	}
+	closing := s.offset(params.Closing)
+	return true, s.splice(closing, closing, param)
+}
+
+// addKeyValue adds "key: value" to the composite literal of type typeName
+// (e.g. the &XxxController{} returned by a constructor) inside function funcName
+func (s *goSource) addKeyValue(funcName, typeName, key, value string) (bool, error) {
+	fn, err := s.funcDecl(funcName)
+	if err != nil {
+		return false, err
+	}
+	var lit *ast.CompositeLit
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if cl, ok := n.(*ast.CompositeLit); ok && lit == nil {
+			if ident, ok := cl.Type.(*ast.Ident); ok && ident.Name == typeName {
+				lit = cl
+			}
+		}
+		return lit == nil
+	})
+	if lit == nil {
+		return false, fmt.Errorf("%s{} literal not found in %s", typeName, funcName)
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return false, fmt.Errorf("%s{} in %s uses positional fields", typeName, funcName)
+		}
+		if ident, ok := kv.Key.(*ast.Ident); ok && ident.Name == key {
+			return false, nil
+		}
+	}
+	entry := key + ": " + value
+	if len(lit.Elts) == 0 {
+		lbrace, rbrace := s.offset(lit.Lbrace), s.offset(lit.Rbrace)
+		return true, s.splice(lbrace+1, rbrace, "\n\t"+entry+",\n")
+	}
+	last := s.offset(lit.Elts[len(lit.Elts)-1].End())
+	return true, s.splice(last, last, ",\n\t"+entry)
+}
+
+// addCallArg adds expr to the arguments of the callee(...) call inside
+// funcName (e.g. wire.Build in NewAppContainer), above the argument before
+// when present, otherwise after the last argument
+func (s *goSource) addCallArg(funcName, callee, expr, before string) (bool, error) {
+	fn, err := s.funcDecl(funcName)
+	if err != nil {
+		return false, err
+	}
+	var call *ast.CallExpr
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if c, ok := n.(*ast.CallExpr); ok && call == nil && compact(s.text(c.Fun)) == callee {
+			call = c
+		}
+		return call == nil
+	})
+	if call == nil {
+		return false, fmt.Errorf("%s call not found in %s", callee, funcName)
+	}
+	for _, arg := range call.Args {
+		if compact(s.text(arg)) == compact(expr) {
+			return false, nil
+		}
+	}
+	for _, arg := range call.Args {
+		if before != "" && compact(s.text(arg)) == compact(before) {
+			at := s.offset(arg.Pos())
+			return true, s.splice(at, at, expr+",\n\t")
+		}
+	}
+	if len(call.Args) == 0 {
+		at := s.offset(call.Rparen)
+		return true, s.splice(at, at, expr)
+	}
+	last := s.offset(call.Args[len(call.Args)-1].End())
+	return true, s.splice(last, last, ",\n\t"+expr)
+}
+
+// callStmt returns the statement in fn calling callee, or nil
+func callStmt(fn *ast.FuncDecl, callee string) ast.Stmt {
+	for _, stmt := range fn.Body.List {
+		expr, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		if call, ok := expr.X.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == callee {
+				return stmt
+			}
+		}
+	}
+	return nil
+}
+
+// routesMarker is the comment in SetupRoutes that generated registrations are inserted above
+const routesMarker = "(auto-generated: add more RegisterXxxRoutes here)"
+
+// addCall inserts the statement "callee(args)" into funcName.
+//
+// The call goes above the routes marker comment when present, otherwise
+// above the first call to before (if not empty), otherwise at the end.
+func (s *goSource) addCall(funcName, callee, args, before string) (bool, error) {
+	fn, err := s.funcDecl(funcName)
+	if err != nil {
+		return false, err
+	}
+	if callStmt(fn, callee) != nil {
+		return false, nil
+	}
+
+	at := -1
+	for _, group := range s.file.Comments {
+		if group.Pos() > fn.Body.Lbrace && group.End() < fn.Body.Rbrace && strings.Contains(group.Text(), routesMarker) {
+			at = s.lineStart(s.offset(group.Pos()))
+			break
+		}
+	}
+	if at < 0 && before != "" {
+		if stmt := callStmt(fn, before); stmt != nil {
+			at = s.lineStart(s.offset(stmt.Pos()))
+		}
+	}
+	if at < 0 {
+		at = s.lineStart(s.offset(fn.Body.Rbrace))
+	}
+	return true, s.splice(at, at, "\t"+callee+"("+args+")\n")
+}
+
+// findTypeFile returns the Go file in dir that declares the type typeName
+func findTypeFile(dir, typeName string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	for _, path := range files {
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return "", err
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if spec.(*ast.TypeSpec).Name.Name == typeName {
+					return path, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("type %s not found in %s", typeName, dir)
+}
+
+// editGoFile loads path, applies edit and saves the file if edit changed it
+func editGoFile(path string, edit func(s *goSource) (bool, error)) (bool, error) {
+	s, err := loadGoSource(path)
+	if err != nil {
+		return false, err
+	}
+	changed, err := edit(s)
+	if err != nil || !changed {
+		return false, err
+	}
+	return true, s.save()
+}
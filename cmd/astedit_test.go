@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoSourceEditsAreIdempotent(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		edit func(s *goSource) (bool, error)
+		want string
+	}{
+		{
+			name: "addProvider",
+			src: `package di
+
+var ProviderSet = wire.NewSet(
+	NewBaseController,
+)
+`,
+			edit: func(s *goSource) (bool, error) { return s.addProvider("ProviderSet", "NewUserController") },
+			want: `package di
+
+var ProviderSet = wire.NewSet(
+	NewBaseController,
+	NewUserController,
+)
+`,
+		},
+		{
+			name: "addProvider to an empty set",
+			src: `package di
+
+var ProviderSet = wire.NewSet()
+`,
+			edit: func(s *goSource) (bool, error) { return s.addProvider("ProviderSet", "NewUserController") },
+			want: `package di
+
+var ProviderSet = wire.NewSet(
+	NewUserController,
+)
+`,
+		},
+		{
+			name: "addStructField",
+			src: `package di
+
+type AppContainer struct {
+	Config *config.Manager // reloaded on change
+}
+`,
+			edit: func(s *goSource) (bool, error) {
+				return s.addStructField("AppContainer", "UserController", "*controllers.UserController")
+			},
+			want: `package di
+
+type AppContainer struct {
+	Config         *config.Manager // reloaded on change
+	UserController *controllers.UserController
+}
+`,
+		},
+		{
+			name: "addImport",
+			src: `package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+`,
+			edit: func(s *goSource) (bool, error) { return s.addImport("time") },
+			want: `package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+`,
+		},
+		{
+			name: "addImport to a single import",
+			src: `package routes
+
+import "github.com/gofiber/fiber/v2"
+`,
+			edit: func(s *goSource) (bool, error) { return s.addImport("time") },
+			want: `package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+`,
+		},
+		{
+			name: "addParam",
+			src: `package services
+
+func NewUserService(base *BaseService) *UserService {
+	return &UserService{BaseService: base}
+}
+`,
+			edit: func(s *goSource) (bool, error) { return s.addParam("NewUserService", "c", "*cache.Cache") },
+			want: `package services
+
+func NewUserService(base *BaseService, c *cache.Cache) *UserService {
+	return &UserService{BaseService: base}
+}
+`,
+		},
+		{
+			name: "addKeyValue",
+			src: `package services
+
+func NewUserService(base *BaseService, c *cache.Cache) *UserService {
+	return &UserService{
+		BaseService: base,
+	}
+}
+`,
+			edit: func(s *goSource) (bool, error) { return s.addKeyValue("NewUserService", "UserService", "Cache", "c") },
+			want: `package services
+
+func NewUserService(base *BaseService, c *cache.Cache) *UserService {
+	return &UserService{
+		BaseService: base,
+		Cache:       c,
+	}
+}
+`,
+		},
+		{
+			name: "addCallArg before an argument",
+			src: `package di
+
+func NewAppContainer() *AppContainer {
+	wire.Build(
+		InfraSet,
+		wire.Struct(new(AppContainer), "*"),
+	)
+	return nil
+}
+`,
+			edit: func(s *goSource) (bool, error) {
+				return s.addCallArg("NewAppContainer", "wire.Build", "repositories.ProviderSet", `wire.Struct(new(AppContainer),"*")`)
+			},
+			want: `package di
+
+func NewAppContainer() *AppContainer {
+	wire.Build(
+		InfraSet,
+		repositories.ProviderSet,
+		wire.Struct(new(AppContainer), "*"),
+	)
+	return nil
+}
+`,
+		},
+		{
+			name: "addCall above the routes marker",
+			src: `package routes
+
+func SetupRoutes(app fiber.Router, c *di.AppContainer) {
+	RegisterRoutes(app, c)
+	// (auto-generated: add more RegisterXxxRoutes here)
+}
+`,
+			edit: func(s *goSource) (bool, error) { return s.addCall("SetupRoutes", "RegisterUserRoutes", "app, c", "") },
+			want: `package routes
+
+func SetupRoutes(app fiber.Router, c *di.AppContainer) {
+	RegisterRoutes(app, c)
+	RegisterUserRoutes(app, c)
+	// (auto-generated: add more RegisterXxxRoutes here)
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &goSource{path: "test.go"}
+			if err := s.reset([]byte(tt.src)); err != nil {
+				t.Fatal(err)
+			}
+			changed, err := tt.edit(s)
+			if err != nil || !changed {
+				t.Fatalf("first edit = %v, %v, want true, nil", changed, err)
+			}
+			changed, err = tt.edit(s)
+			if err != nil || changed {
+				t.Fatalf("second edit = %v, %v, want false, nil", changed, err)
+			}
+			if got := string(s.src); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddStructFieldRejectsOtherType(t *testing.T) {
+	s := &goSource{path: "test.go"}
+	if err := s.reset([]byte("package di\n\ntype AppContainer struct {\n\tUserController *UserController\n}\n")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s.addStructField("AppContainer", "UserController", "*controllers.UserController")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("err = %v, want an already exists error", err)
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configEnv  string
+	configFile string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration of the project",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print [-- project flags]",
+	Short: "Print the effective configuration with the source of every value",
+	Long: `Print the configuration the project would load for --env, merged the same
+way config.New() merges it (later sources win):
+
+  1. defaults from the tags of config.Config
+  2. config.yaml
+  3. env files: .env.dev + .env.local (dev), .env + .env.prod (prod),
+     .env.<env> otherwise, then the encrypted .env.<env>.enc
+  4. environment variables
+  5. project flags, passed after --
+
+Passwords, secrets, tokens and keys are redacted.
+
+Examples:
+  nvs config print
+  nvs config print --env prod
+  nvs config print --env staging -- --port=9000`,
+	Run: func(cmd *cobra.Command, args []string) {
+		origin := "default"
+		if cmd.Flags().Changed("env") {
+			origin = "flags"
+		} else if os.Getenv("ENV") != "" {
+			origin = "environment"
+		}
+		sources, err := resolveProjectSources(configEnv, origin, configFile, args)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		fields, err := projectConfigFields("config")
+		if err != nil {
+			fmt.Println("❌ Cannot read config.Config (run nvs from the project root):", err)
+			os.Exit(1)
+		}
+
+		files := sources.files()
+		if len(files) == 0 {
+			fmt.Printf("ℹ️  No configuration files found for %s, showing defaults and environment variables\n\n", configEnv)
+		} else {
+			fmt.Printf("ℹ️  Environment %s (%s), files: %v\n\n", configEnv, origin, files)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		known := map[string]bool{}
+		var missing []string
+		for _, f := range fields {
+			known[f.Key] = true
+			value, source, ok := sources.lookup(f.Key)
+			if !ok {
+				value, source = f.Default, "default"
+			}
+			if value == "" && f.Required {
+				missing = append(missing, f.Key)
+				value = "<missing>"
+			} else {
+				value = redactConfigValue(f.Key, value, f.Secret)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", f.Key, value, source)
+		}
+		// keys the files set that config.Config does not declare
+		for _, key := range sources.fileKeys() {
+			if known[key] {
+				continue
+			}
+			value, source, _ := sources.lookup(key)
+			fmt.Fprintf(w, "%s\t%s\t%s (not in config.Config)\n", key, redactConfigValue(key, value, false), source)
+		}
+		w.Flush()
+
+		if len(missing) > 0 {
+			fmt.Printf("\n⚠️ Missing required keys: %v\n", missing)
+		}
+	},
+}
+
+// redactConfigValue hides secrets and the password of URLs
+func redactConfigValue(key, value string, secret bool) string {
+	if value == "" {
+		return ""
+	}
+	if secret || isSecretKey(key) {
+		return "********"
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+	return value
+}
+
+func init() {
+	configPrintCmd.Flags().StringVarP(&configEnv, "env", "e", defaultProjectEnv(), "Environment to resolve (dev, staging, prod)")
+	configPrintCmd.Flags().StringVar(&configFile, "config", projectConfigFile, "YAML configuration file")
+	configCmd.AddCommand(configPrintCmd)
+	RootCmd.AddCommand(configCmd)
+}
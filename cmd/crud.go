@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// crudFieldType describes how a --fields type maps onto Postgres and the Go
+// types sqlc generates for it (with the overrides in sqlc.yaml)
+type crudFieldType struct {
+	SQL      string
+	GoType   string
+	Import   string
+	SwagType string
+}
+
+var crudFieldTypes = map[string]crudFieldType{
+	"string":  {SQL: "VARCHAR(255)", GoType: "string"},
+	"text":    {SQL: "TEXT", GoType: "string"},
+	"int":     {SQL: "INTEGER", GoType: "int32"},
+	"int32":   {SQL: "INTEGER", GoType: "int32"},
+	"int64":   {SQL: "BIGINT", GoType: "int64"},
+	"float":   {SQL: "DOUBLE PRECISION", GoType: "float64"},
+	"decimal": {SQL: "NUMERIC(12, 2)", GoType: "decimal.Decimal", Import: "github.com/shopspring/decimal", SwagType: "string"},
+	"bool":    {SQL: "BOOLEAN", GoType: "bool"},
+	"time":    {SQL: "TIMESTAMPTZ", GoType: "pgtype.Timestamptz", Import: "github.com/jackc/pgx/v5/pgtype", SwagType: "string"},
+	"date":    {SQL: "DATE", GoType: "pgtype.Date", Import: "github.com/jackc/pgx/v5/pgtype", SwagType: "string"},
+	"uuid":    {SQL: "UUID", GoType: "pgtype.UUID", Import: "github.com/jackc/pgx/v5/pgtype", SwagType: "string"},
+}
+
+type crudField struct {
+	Name   string // Go field name as generated by sqlc (e.g. CategoryID)
+	Column string // snake_case column name
+	Type   crudFieldType
+}
+
+type crudResource struct {
+	ModuleName  string
+	Name        string // PascalCase singular (e.g. Product)
+	Plural      string // PascalCase plural (e.g. Products)
+	Var         string // camelCase singular (e.g. product)
+	RouteVar    string // controller variable of the route file, Var unless it clashes
+	Label       string // human readable singular (e.g. product category)
+	LabelPlural string // human readable plural (e.g. product categories)
+	Table       string // snake_case plural table name (e.g. products)
+	Path        string // URL segment (e.g. products)
+	Fields      []crudField
+	Imports     []string
+}
+
+var crudFields string
+var crudForce bool
+
+var generateCrudCmd = &cobra.Command{
+	Use:   "crud [resource]",
+	Short: "Generate migration, queries, service, controller and routes for a resource",
+	Long: `Generate a full CRUD vertical slice for a resource.
+
+Creates a migration, sqlc query file, service with DTOs, controller with
+List/Get/Create/Update/Delete handlers and swag annotations, and a route file,
+then registers the providers, AppContainer field and routes.
+
+Supported field types: ` + strings.Join(crudFieldTypeNames(), ", ") + `
+
+Examples:
+  nvs generate crud product --fields "name:string,price:decimal,active:bool"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		res, err := newCrudResource(args[0])
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if err := res.parseFields(crudFields); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		res.ModuleName = readModuleName()
+		if res.ModuleName == "" {
+			fmt.Println("❌ Cannot read module name from go.mod")
+			return
+		}
+
+		version, err := nextMigrationVersion("migrations")
+		if err != nil {
+			fmt.Println("❌ Cannot read migrations directory:", err)
+			return
+		}
+		migrationBase := filepath.Join("migrations", fmt.Sprintf("%06d_create_%s_table", version, res.Table))
+
+		files := []struct {
+			path  string
+			tmpl  string
+			gofmt bool
+		}{
+			{migrationBase + ".up.sql", "crud/migration.up.sql.tmpl", false},
+			{migrationBase + ".down.sql", "crud/migration.down.sql.tmpl", false},
+			{filepath.Join("queries", res.Table+".sql"), "crud/queries.sql.tmpl", false},
+			{filepath.Join("api", "v1", "services", toSnakeCase(res.Name)+".go"), "crud/service.go.tmpl", true},
+			{filepath.Join("api", "v1", "controllers", toSnakeCase(res.Name)+".go"), "crud/controller.go.tmpl", true},
+			{filepath.Join("api", "v1", "routes", toSnakeCase(res.Name)+"_route.go"), "crud/route.go.tmpl", true},
+		}
+
+		if !crudForce {
+			for _, f := range files {
+				if _, err := os.Stat(f.path); err == nil {
+					fmt.Println("❌ File already exists (use --force to overwrite):", f.path)
+					return
+				}
+			}
+		}
+
+		for _, f := range files {
+			if err := renderTemplateFile(f.path, f.tmpl, res, f.gofmt); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			fmt.Printf("✅ Created: %s\n", f.path)
+		}
+
+		if _, err := ensureRepository(); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		servicesProviders := filepath.Join("api", "v1", "services", "providers.go")
+		if _, err := registerProvider(servicesProviders, "New"+res.Name+"Service"); err != nil {
+			fmt.Println("❌ Cannot read services providers.go:", err)
+			return
+		}
+		controllersProviders := filepath.Join("api", "v1", "controllers", "providers.go")
+		if _, err := registerProvider(controllersProviders, "New"+res.Name+"Controller"); err != nil {
+			fmt.Println("❌ Cannot read controllers providers.go:", err)
+			return
+		}
+		addToAppContainer("controllers", res.Name+"Controller")
+		updateSetupRoutes(filepath.Join("api", "v1", "routes"))
+
+		// sqlc must run before wire, otherwise the services package does not compile
+		if _, err := exec.LookPath("sqlc"); err == nil {
+			if err := runSqlc(); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			runWire()
+		} else {
+			fmt.Println("⚠️ sqlc not found, skipping code generation")
+			fmt.Println("👉 Run the following commands to finish:")
+			fmt.Println("  1. sqlc generate")
+			fmt.Println("  2. wire ./di")
+		}
+
+		for _, imp := range res.Imports {
+			if imp == "github.com/shopspring/decimal" {
+				fmt.Println("👉 Run `go get github.com/shopspring/decimal` if it is not in go.mod yet")
+			}
+		}
+	},
+}
+
+// checkName returns an error unless name can be spliced into Go identifiers
+// and file names by the generators
+func checkName(kind, name string) error {
+	if !regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`).MatchString(name) {
+		return fmt.Errorf("invalid %s name: %q", kind, name)
+	}
+	return nil
+}
+
+// newCrudResource derives the type, table and route names of a resource
+func newCrudResource(name string) (*crudResource, error) {
+	if err := checkName("resource", name); err != nil {
+		return nil, err
+	}
+	singular := toSnakeCase(name)
+	res := &crudResource{
+		Name:  toPascalCase(singular),
+		Table: pluralize(singular),
+	}
+	res.Plural = toPascalCase(res.Table)
+	res.Var = lowerFirst(res.Name)
+	res.RouteVar = routeVarName(res.Name, nil)
+	res.Path = strings.ReplaceAll(res.Table, "_", "-")
+	res.Label = strings.ReplaceAll(singular, "_", " ")
+	res.LabelPlural = strings.ReplaceAll(res.Table, "_", " ")
+	return res, nil
+}
+
+// parseFields parses the --fields flag (name:type,...)
+func (res *crudResource) parseFields(fields string) error {
+	if strings.TrimSpace(fields) == "" {
+		return fmt.Errorf("at least one field is required (e.g. --fields \"name:string,price:decimal\")")
+	}
+
+	imports := map[string]bool{}
+	seen := map[string]bool{}
+	for _, raw := range strings.Split(fields, ",") {
+		parts := strings.SplitN(strings.TrimSpace(raw), ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid field %q, expected name:type", raw)
+		}
+		column := toSnakeCase(parts[0])
+		typ, ok := crudFieldTypes[strings.ToLower(parts[1])]
+		if !ok {
+			return fmt.Errorf("unsupported type %q for field %s (supported: %s)", parts[1], parts[0], strings.Join(crudFieldTypeNames(), ", "))
+		}
+		switch column {
+		case "id", "created_at", "updated_at":
+			return fmt.Errorf("field %s is generated automatically", column)
+		}
+		if seen[column] {
+			return fmt.Errorf("duplicate field %s", column)
+		}
+		seen[column] = true
+		if typ.Import != "" {
+			imports[typ.Import] = true
+		}
+		res.Fields = append(res.Fields, crudField{
+			Name:   toPascalCase(column),
+			Column: column,
+			Type:   typ,
+		})
+	}
+
+	// created_at/updated_at are always pgtype.Timestamptz
+	imports["github.com/jackc/pgx/v5/pgtype"] = true
+	for imp := range imports {
+		res.Imports = append(res.Imports, imp)
+	}
+	sort.Strings(res.Imports)
+	return nil
+}
+
+// nextMigrationVersion returns the version following the highest NNNNNN_ prefix in dir
+func nextMigrationVersion(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	max := 0
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.Atoi(prefix); err == nil && v > max {
+			max = v
+		}
+	}
+	return max + 1, nil
+}
+
+func crudFieldTypeNames() []string {
+	names := make([]string, 0, len(crudFieldTypes))
+	for name := range crudFieldTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Helper: convert snake_case or camelCase to PascalCase the way sqlc names fields
+func toPascalCase(str string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(toSnakeCase(str), "_") {
+		if part == "" {
+			continue
+		}
+		if part == "id" {
+			b.WriteString("ID")
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// Helper: naive English pluralization for table names
+func pluralize(word string) string {
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsAny(word[len(word)-2:len(word)-1], "aeiou"):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+func init() {
+	generateCrudCmd.Flags().StringVar(&crudFields, "fields", "", "Comma separated name:type list (e.g. name:string,price:decimal,active:bool)")
+	generateCrudCmd.Flags().BoolVarP(&crudForce, "force", "f", false, "Overwrite existing files")
+	generateCmd.AddCommand(generateCrudCmd)
+}
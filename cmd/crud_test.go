@@ -0,0 +1,23 @@
+package cmd
+
+import "testing"
+
+func TestCrudRouteVar(t *testing.T) {
+	for name, want := range map[string]string{"product": "product", "type": "typeController", "map": "mapController", "app": "appController", "di": "diController"} {
+		res, err := newCrudResource(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.RouteVar != want {
+			t.Errorf("newCrudResource(%q).RouteVar = %q, want %q", name, res.RouteVar, want)
+		}
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	for name, valid := range map[string]bool{"payment": true, "order_item": true, "OrderItem2": true, "my-svc": false, "1cache": false, "": false, "a.b": false} {
+		if err := checkName("service", name); (err == nil) != valid {
+			t.Errorf("checkName(%q) = %v, want valid %v", name, err, valid)
+		}
+	}
+}
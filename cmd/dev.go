@@ -50,7 +50,7 @@ var devCmd = &cobra.Command{
 			// บน Windows อาจต้อง restart terminal หรือ add Go bin to PATH
 			if runtime.GOOS == "windows" {
 				fmt.Println("💡 On Windows, you may need to restart your terminal or add Go bin to PATH")
-				fmt.Println("   Go bin path is usually: %GOPATH%\\bin or %GOROOT%\\bin")
+				fmt.Printf("   Go bin path is usually: %s\n", `%GOPATH%\bin or %GOROOT%\bin`)
 			}
 			return
 		}
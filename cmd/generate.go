@@ -3,12 +3,13 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"go/ast"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
-	"text/template"
 
 	"github.com/spf13/cobra"
 )
@@ -20,7 +21,7 @@ var generateCmd = &cobra.Command{
 
 var generateRouteCmd = &cobra.Command{
 	Use:   "route [name]",
-	Short: "Generate a new route file",
+	Short: "Generate a new route file and register it in SetupRoutes",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		routeName := args[0]
@@ -29,152 +30,110 @@ var generateRouteCmd = &cobra.Command{
 			return
 		}
 
-		// Capitalize first letter for function name
-		routeNameTitle := strings.Title(routeName)
-
-		tmplPath := filepath.Join("templates", "api", "v1", "routes", "route.go.tmpl")
-		outputDir := filepath.Join("api", "v1", "routes")
-		outputFile := filepath.Join(outputDir, routeName+".go")
-
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			fmt.Println("❌ Cannot create routes directory:", err)
-			return
-		}
-
-		tmpl, err := template.ParseFiles(tmplPath)
-		if err != nil {
-			fmt.Println("❌ Cannot read template:", err)
-			return
-		}
-
-		file, err := os.Create(outputFile)
-		if err != nil {
-			fmt.Println("❌ Cannot create route file:", err)
+		routesDir := filepath.Join("api", "v1", "routes")
+		outputFile := filepath.Join(routesDir, toSnakeCase(routeName)+"_route.go")
+		if _, err := os.Stat(outputFile); err == nil {
+			fmt.Println("❌ Route file already exists:", outputFile)
 			return
 		}
-		defer file.Close()
 
 		data := map[string]string{
-			"RouteName": routeNameTitle,
+			"ModuleName": readModuleName(),
+			"RouteName":  toPascalCase(routeName),
 		}
-
-		if err := tmpl.Execute(file, data); err != nil {
-			fmt.Println("❌ Cannot render template:", err)
+		if err := renderTemplateFile(outputFile, "route/route.go.tmpl", data, true); err != nil {
+			fmt.Println("❌", err)
 			return
 		}
-
 		fmt.Printf("✅ Created route: %s\n", outputFile)
+
+		updateSetupRoutes(routesDir)
 	},
 }
 
 var generateRoutesCmd = &cobra.Command{
 	Use:   "routes",
-	Short: "Auto-generate route files from controller @Router/@Tags comments",
+	Short: "Auto-generate route files from controller @Router comments",
+	Long: `Generate one route file per controller from the swag annotations of its
+handlers.
+
+Every "// @Router /api/v1/products/{id} [get]" line on a method taking
+*fiber.Ctx becomes app.Get("/products/:id", ...) in
+api/v1/routes/<controller>_route.go. A handler may have several @Router lines.
+Routes are sorted with static segments before parameters, so the output is
+stable across runs. BaseController is skipped because RegisterRoutes in base.go
+wires it by hand.
+
+Handlers can declare their middleware next to the @Router line; the chain is
+applied in the order written:
+
+  // @Auth        bearer                 -> mw.Auth("Bearer")
+  // @Middleware  RateLimit(Tier4, 1m)   -> mw.RateLimit(constants.Tier4, time.Minute)
+  // @Middleware  Audit                  -> c.AuditMiddleware.Handle()
+
+where mw is the container's BaseMiddleware.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		controllersDir := filepath.Join("api", "v1", "controllers")
 		routesDir := filepath.Join("api", "v1", "routes")
-		os.MkdirAll(routesDir, 0755)
 
-		controllerFiles, _ := filepath.Glob(filepath.Join(controllersDir, "*.go"))
-		if len(controllerFiles) == 0 {
-			fmt.Println("❌ No controller files found.")
+		groups, err := scanControllerRoutes(controllersDir)
+		if err != nil {
+			fmt.Println("❌ Cannot read controllers:", err)
+			return
+		}
+		if len(groups) == 0 {
+			fmt.Println("❌ No controller routes found.")
 			return
 		}
 
-		// Regex for @Router, @Tags, and method signature
-		reRouter := regexp.MustCompile(`@Router\s+([^\s]+) \[([a-zA-Z]+)\]`)
-		reTag := regexp.MustCompile(`@Tags\s+([A-Za-z0-9_]+)`)
-		reFunc := regexp.MustCompile(`func \(.*\*([A-Za-z0-9_]+)\) ([A-Za-z0-9_]+)\(.*\*fiber.Ctx.*\) error`)
-
-		tagRoutes := map[string][]map[string]string{} // tag => []{method, path, handler, controller}
+		middleware, err := loadRouteMiddleware()
+		if err != nil {
+			fmt.Println("❌ Cannot read middleware:", err)
+			return
+		}
 
-		for _, file := range controllerFiles {
-			f, err := os.Open(file)
+		moduleName := readModuleName()
+		for _, group := range groups {
+			if group.Controller == "BaseController" {
+				continue // registered by RegisterRoutes in base.go
+			}
+			usesBase, needs, err := middleware.resolve(group)
 			if err != nil {
-				fmt.Println("❌ Cannot open:", file)
+				fmt.Println("❌", err)
 				continue
 			}
-			scanner := bufio.NewScanner(f)
-			var lastTag, lastRouter, lastMethod, lastHandler, lastController string
-			for scanner.Scan() {
-				line := scanner.Text()
-				if m := reTag.FindStringSubmatch(line); m != nil {
-					lastTag = m[1]
+			var stdImports []string
+			imports := []string{moduleName + "/di"}
+			for _, pkg := range needs {
+				if pkg == "time" {
+					stdImports = append(stdImports, pkg)
+				} else {
+					imports = append(imports, moduleName+"/"+pkg)
 				}
-				if m := reRouter.FindStringSubmatch(line); m != nil {
-					lastRouter = m[1]
-					lastMethod = strings.ToUpper(m[2])
-				}
-				if m := reFunc.FindStringSubmatch(line); m != nil {
-					lastController = m[1]
-					lastHandler = m[2]
-					if lastTag != "" && lastRouter != "" && lastMethod != "" {
-						tag := lastTag
-						route := map[string]string{
-							"method":     lastMethod,
-							"path":       lastRouter,
-							"handler":    lastHandler,
-							"controller": lastController,
-						}
-						tagRoutes[tag] = append(tagRoutes[tag], route)
-						// Reset for next
-						lastRouter, lastMethod, lastHandler, lastController = "", "", "", ""
-						lastTag = ""
-					}
+			}
+			sort.Strings(imports)
+			for _, r := range group.Routes {
+				if !r.Prefixed {
+					fmt.Printf("⚠️ %s: %s is not under %s, it is registered as %s%s\n", r.Pos, r.Path, apiPrefix, apiPrefix, r.Route)
 				}
 			}
-			f.Close()
-		}
-
-		// Read module name from go.mod
-		moduleName := readModuleName()
 
-		// For each tag, generate a route file (skip Base)
-		for tag, routes := range tagRoutes {
-			if strings.ToLower(tag) == "base" {
-				continue // do not generate/overwrite base.go
-			}
-			// Remove 'controller' or 'Controller' suffix from tag for filename
-			baseTag := tag
-			if strings.HasSuffix(strings.ToLower(baseTag), "controller") {
-				baseTag = baseTag[:len(baseTag)-10]
-			}
-			snakeTag := toSnakeCase(baseTag)
-			fileName := filepath.Join(routesDir, snakeTag+"_route.go")
-			file, err := os.Create(fileName)
-			if err != nil {
-				fmt.Println("❌ Cannot create:", fileName)
-				continue
-			}
-			tmpl := template.New("route").Funcs(template.FuncMap{
-				"title": func(s string) string {
-					if len(s) == 0 {
-						return s
-					}
-					return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
-				},
-			})
-			tmpl = template.Must(tmpl.Parse(routeFileTemplate))
+			fileName := filepath.Join(routesDir, toSnakeCase(group.Name)+"_route.go")
 			data := map[string]interface{}{
-				"Tag":        strings.Split(tag, "Controller")[0],
-				"Routes":     routes,
 				"ModuleName": moduleName,
+				"Group":      group,
+				"StdImports": stdImports,
+				"Imports":    imports,
+				"Middleware": "",
 			}
-			tmpl.Execute(file, data)
-			file.Close()
-			fmt.Printf("✅ Generated: %s\n", fileName)
-
-			// Remove controller name from route path in each route
-			for i, r := range routes {
-				if path, ok := r["path"]; ok {
-					// Remove /Tag or /TagController from the path
-					cleanPath := regexp.MustCompile(`/`+tag+`(Controller)?`).ReplaceAllString(path, "")
-					if cleanPath == "" {
-						cleanPath = "/"
-					}
-					routes[i]["path"] = cleanPath
-				}
+			if usesBase {
+				data["Middleware"] = middleware.baseField
+			}
+			if err := renderTemplateFile(fileName, "routes/route_file.go.tmpl", data, true); err != nil {
+				fmt.Println("❌", err)
+				continue
 			}
+			fmt.Printf("✅ Generated: %s (%d routes)\n", fileName, len(group.Routes))
 		}
 
 		// After generating route files, update SetupRoutes in base.go
@@ -192,108 +151,41 @@ var generateControllerCmd = &cobra.Command{
 			fmt.Println("❌ Controller name is required")
 			return
 		}
-		controllerName := strings.Title(name) + "Controller"
-		fileName := filepath.Join("api", "v1", "controllers", strings.ToLower(name)+".go")
+		res, err := newCrudResource(name)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		res.ModuleName = readModuleName()
+		controllerName := res.Name + "Controller"
+		fileName := filepath.Join("api", "v1", "controllers", toSnakeCase(res.Name)+".go")
 		if _, err := os.Stat(fileName); err == nil {
 			fmt.Println("❌ Controller file already exists:", fileName)
 			return
 		}
-		// Generate controller file
-		controllerTmpl := `package controllers
-
-import (
-	"github.com/gofiber/fiber/v2"
-)
-
-type ` + controllerName + ` struct {}
-
-func New` + controllerName + `() *` + controllerName + ` {
-	return &` + controllerName + `{}
-}
-
-// Exemple Function
-// @Summary      Get Server Info
-// @Description  Get server info and dependencies status and uptime of server and more
-// @Tags         ` + controllerName + `
-// @Produce      json
-// @Success      200				 {object}  services.ServerInfoResponse
-// @Router       /api/v1/` + controllerName + `/info [get]
-func (c *` + controllerName + `) Example(ctx *fiber.Ctx) error {
-	return ctx.SendString("Hello from ` + controllerName + `")
-}
-`
-		os.WriteFile(fileName, []byte(controllerTmpl), 0644)
+		if err := renderTemplateFile(fileName, "controller/controller.go.tmpl", res, true); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
 		fmt.Printf("✅ Created controller: %s\n", fileName)
 
 		// Register in providers.go
 		providersFile := filepath.Join("api", "v1", "controllers", "providers.go")
-		providersContent, err := os.ReadFile(providersFile)
+		added, err := registerProvider(providersFile, "New"+controllerName)
 		if err != nil {
 			fmt.Println("❌ Cannot read providers.go:", err)
 			return
 		}
-		providersStr := string(providersContent)
-		// Add NewXxxController to ProviderSet if not exists
-		added := false
-		if !strings.Contains(providersStr, "New"+controllerName) {
-			reSet := regexp.MustCompile(`(?s)(var ProviderSet = wire.NewSet\()(.*?)(\))`)
-			providersStr = reSet.ReplaceAllString(providersStr, "${1}\n\tNew"+controllerName+",${2}${3}")
-			os.WriteFile(providersFile, []byte(providersStr), 0644)
-			fmt.Println("✅ Registered New" + controllerName + " in ProviderSet")
-			added = true
-		} else {
-			fmt.Println("ℹ️  New" + controllerName + " already registered in ProviderSet")
-		}
 
 		// Add XxxController to AppContainer struct in di/wire.go
-		addedField := addControllerToAppContainer(controllerName)
+		addedField := addToAppContainer("controllers", controllerName)
 		if added || addedField {
 			// Run wire ./di only if something was added
-			wireCmd := exec.Command("wire", "./di")
-			wireCmd.Stdout = os.Stdout
-			wireCmd.Stderr = os.Stderr
-			if err := wireCmd.Run(); err != nil {
-				fmt.Println("❌ Cannot run wire:", err)
-			} else {
-				fmt.Println("✅ wire ./di completed")
-			}
+			runWire()
 		}
 	},
 }
 
-const routeFileTemplate = `/*
- * Created on Tue Mar 04 2025
- *
- * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
- *
- * * ข้อมูลลับและสงวนสิทธิ์ *
- * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
- * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
- * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
- *
- * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
- * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
- * และกฎหมายอื่นที่เกี่ยวข้อง
- */
- 
- package routes
-
-import (
-	"github.com/gofiber/fiber/v2"
-	"{{ .ModuleName }}/di"
-)
-
-func Register{{ .Tag }}Routes(app fiber.Router, c *di.AppContainer) {
-    {{ $tag := .Tag }}
-    {{ $ctrl := printf "%s" .Tag }}
-    {{ $inst := printf "%s := c.%sController" $tag $tag }}
-    {{ $inst }}
-{{- range .Routes }}
-	app.{{ .method | title }}("{{ .path }}", {{ $tag }}.{{ .handler }})
-{{- end }}
-}
-`
-
 func init() {
 	generateCmd.AddCommand(generateRouteCmd)
 	generateCmd.AddCommand(generateRoutesCmd)
@@ -330,79 +222,132 @@ func readModuleName() string {
 func updateSetupRoutes(routesDir string) {
 	baseFile := filepath.Join(routesDir, "base.go")
 	files, _ := filepath.Glob(filepath.Join(routesDir, "*.go"))
-	var registerCalls []string
+	sort.Strings(files)
+	var registerFuncs []string
 	for _, f := range files {
-		if strings.HasSuffix(f, "base.go") {
+		if filepath.Base(f) == "base.go" {
+			continue
+		}
+		src, err := loadGoSource(f)
+		if err != nil {
+			fmt.Println("❌", err)
 			continue
 		}
-		content, _ := os.ReadFile(f)
-		re := regexp.MustCompile(`func (Register([A-Za-z0-9_]+)Routes)\s*\(`)
-		matches := re.FindAllStringSubmatch(string(content), -1)
-		for _, m := range matches {
-			if m[1] == "RegisterRoutes" {
+		for _, decl := range src.file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !reRegisterRoutes.MatchString(fn.Name.Name) || fn.Name.Name == "RegisterRoutes" {
 				continue
 			}
-			registerCalls = append(registerCalls, fmt.Sprintf("\t%s(v1API, container)", m[1]))
+			registerFuncs = append(registerFuncs, fn.Name.Name)
 		}
 	}
 
-	baseContent, _ := os.ReadFile(baseFile)
-	// Read base.go and collect existing RegisterXxxRoutes calls
-	baseContentStr := string(baseContent)
-	existing := map[string]bool{}
-	reCall := regexp.MustCompile(`Register([A-Za-z0-9_]+)Routes\(v1API, container\)`) // match function calls
-	for _, m := range reCall.FindAllStringSubmatch(baseContentStr, -1) {
-		existing[m[0]] = true
+	changed, err := editGoFile(baseFile, func(s *goSource) (bool, error) {
+		changed := false
+		for _, name := range registerFuncs {
+			added, err := s.addCall("SetupRoutes", name, "v1API, container", "notFoundRoute")
+			if err != nil {
+				return false, err
+			}
+			changed = changed || added
+		}
+		return changed, nil
+	})
+	if err != nil {
+		fmt.Println("❌ Cannot update SetupRoutes:", err)
+		return
+	}
+	if changed {
+		fmt.Println("✅ Updated RegisterXxxRoutes in base.go")
+	} else {
+		fmt.Println("ℹ️  RegisterXxxRoutes in base.go already up to date")
 	}
+}
 
-	// Only add calls that do not already exist
-	var uniqueCalls []string
-	for _, call := range registerCalls {
-		callTrim := strings.TrimSpace(call)
-		if !existing[callTrim] {
-			uniqueCalls = append(uniqueCalls, callTrim)
-		}
+var reRegisterRoutes = regexp.MustCompile(`^Register[A-Za-z0-9_]+Routes$`)
+
+// Add NewXxx constructor to the ProviderSet in the given providers.go
+// Returns true if the constructor was added
+func registerProvider(providersFile, constructor string) (bool, error) {
+	added, err := editGoFile(providersFile, func(s *goSource) (bool, error) {
+		return s.addProvider("ProviderSet", constructor)
+	})
+	if err != nil {
+		return false, err
 	}
+	if added {
+		fmt.Println("✅ Registered " + constructor + " in ProviderSet")
+	} else {
+		fmt.Println("ℹ️  " + constructor + " already registered in ProviderSet")
+	}
+	return added, nil
+}
 
-	// Replace only the auto-generated line
-	reAuto := regexp.MustCompile(`(?m)^\s*// \(auto-generated: add more RegisterXxxRoutes here\).*$`)
-	newBlock := strings.Join(uniqueCalls, "\n")
-	if newBlock != "" {
-		newBlock += "\n"
+// Run wire ./di to regenerate di/wire_gen.go
+func runWire() {
+	wireCmd := exec.Command("wire", "./di")
+	wireCmd.Stdout = os.Stdout
+	wireCmd.Stderr = os.Stderr
+	if err := wireCmd.Run(); err != nil {
+		fmt.Println("❌ Cannot run wire:", err)
+	} else {
+		fmt.Println("✅ wire ./di completed")
 	}
-	newBlock += "\t// (auto-generated: add more RegisterXxxRoutes here)"
-	result := reAuto.ReplaceAll(baseContent, []byte(newBlock))
-	_ = os.WriteFile(baseFile, result, 0644)
-	fmt.Println("✅ Updated RegisterXxxRoutes in base.go")
 }
 
-// Add XxxController struct field to AppContainer in di/wire.go
+// Add a XxxController or XxxMiddleware field to AppContainer in di/wire.go
+// pkg is the package under api/v1 declaring typeName (controllers or middleware)
 // Returns true if a new field was added
-func addControllerToAppContainer(controllerName string) bool {
+func addToAppContainer(pkg, typeName string) bool {
 	wireFile := filepath.Join("di", "wire.go")
-	content, err := os.ReadFile(wireFile)
+	added, err := editGoFile(wireFile, func(s *goSource) (bool, error) {
+		importAdded, err := s.addImport(readModuleName() + "/api/v1/" + pkg)
+		if err != nil {
+			return false, err
+		}
+		fieldAdded, err := s.addStructField("AppContainer", typeName, "*"+pkg+"."+typeName)
+		return importAdded || fieldAdded, err
+	})
 	if err != nil {
-		fmt.Println("❌ Cannot read di/wire.go:", err)
+		fmt.Println("❌ Cannot update di/wire.go:", err)
 		return false
 	}
-	structField := "\t" + controllerName + " *controllers." + controllerName + "\n"
-	if strings.Contains(string(content), structField) {
-		fmt.Println("ℹ️  " + controllerName + " already exists in AppContainer")
+	if !added {
+		fmt.Println("ℹ️  " + typeName + " already exists in AppContainer")
 		return false
 	}
-	re := regexp.MustCompile(`(?s)(type AppContainer struct \{)(.*?)(\n\})`)
-	newContent := re.ReplaceAllString(string(content), "${1}${2}\n"+structField+"${3}")
-	os.WriteFile(wireFile, []byte(newContent), 0644)
-	fmt.Println("✅ Added " + controllerName + " to AppContainer in di/wire.go")
+	fmt.Println("✅ Added " + typeName + " to AppContainer in di/wire.go")
 	return true
 }
 
+// Check that AppContainer in di/wire.go has no field typeName of another type
+func checkAppContainerField(pkg, typeName string) error {
+	s, err := loadGoSource(filepath.Join("di", "wire.go"))
+	if err != nil {
+		return err
+	}
+	st, err := s.structType("AppContainer")
+	if err != nil {
+		return err
+	}
+	if field := structField(st, typeName); field != nil && compact(s.text(field.Type)) != "*"+pkg+"."+typeName {
+		return fmt.Errorf("AppContainer.%s already exists with type %s", typeName, s.text(field.Type))
+	}
+	return nil
+}
+
 // Helper: convert CamelCase or PascalCase to snake_case
+// Acronyms are kept together (e.g. CategoryID -> category_id)
 func toSnakeCase(str string) string {
+	runes := []rune(str)
 	var result []rune
-	for i, r := range str {
-		if i > 0 && r >= 'A' && r <= 'Z' {
-			result = append(result, '_')
+	for i, r := range runes {
+		if i > 0 && r >= 'A' && r <= 'Z' && runes[i-1] != '_' {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z'
+			if (prev >= 'a' && prev <= 'z') || (prev >= '0' && prev <= '9') || nextLower {
+				result = append(result, '_')
+			}
 		}
 		result = append(result, r)
 	}
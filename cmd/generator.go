@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// generatorsDir is the embedded directory holding generator templates
+const generatorsDir = "generators"
+
+// overrideDir is where a project keeps its customised generator templates.
+// A file at .nvs/templates/<name> replaces the embedded generators/<name>.
+var overrideDir = filepath.Join(".nvs", "templates")
+
+var generatorFuncs = template.FuncMap{
+	"add":   func(a, b int) int { return a + b },
+	"title": title,
+}
+
+// loadGeneratorTemplate returns the source of the generator template name
+// (e.g. "crud/service.go.tmpl"), preferring the project override if present
+func loadGeneratorTemplate(name string) (string, error) {
+	override := filepath.Join(overrideDir, filepath.FromSlash(name))
+	if content, err := os.ReadFile(override); err == nil {
+		return string(content), nil
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("cannot read template override %s: %w", override, err)
+	}
+
+	content, err := templatesFS.ReadFile(path.Join(generatorsDir, name))
+	if err != nil {
+		return "", fmt.Errorf("template %s not found: %w", name, err)
+	}
+	return string(content), nil
+}
+
+// renderTemplateFile renders a generator template to path, running gofmt on Go files
+func renderTemplateFile(path, name string, data interface{}, gofmt bool) error {
+	text, err := loadGeneratorTemplate(name)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(name).Funcs(generatorFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("cannot parse template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("cannot render %s: %w", path, err)
+	}
+
+	content := buf.Bytes()
+	if gofmt {
+		if content, err = format.Source(content); err != nil {
+			return fmt.Errorf("cannot format %s (template %s): %w", path, name, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create directory for %s: %w", path, err)
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// generatorTemplateNames lists the embedded generator templates
+func generatorTemplateNames() ([]string, error) {
+	var names []string
+	err := fs.WalkDir(templatesFS, generatorsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		names = append(names, p[len(generatorsDir)+1:])
+		return nil
+	})
+	return names, err
+}
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List and customise generator templates",
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List generator templates and whether the project overrides them",
+	Run: func(cmd *cobra.Command, args []string) {
+		names, err := generatorTemplateNames()
+		if err != nil {
+			fmt.Println("❌ Cannot list templates:", err)
+			return
+		}
+		for _, name := range names {
+			source := "embedded"
+			if _, err := os.Stat(filepath.Join(overrideDir, filepath.FromSlash(name))); err == nil {
+				source = "override"
+			}
+			fmt.Printf("%-36s %s\n", name, source)
+		}
+	},
+}
+
+var templatesEjectForce bool
+
+var templatesEjectCmd = &cobra.Command{
+	Use:   "eject [name...]",
+	Short: "Copy embedded generator templates to .nvs/templates for customisation",
+	Long: `Copy embedded generator templates to .nvs/templates for customisation.
+
+Without arguments every template is copied. Generators use the copy in
+.nvs/templates instead of the embedded template from then on.
+
+Examples:
+  nvs templates eject                       # Copy all templates
+  nvs templates eject crud/controller.go.tmpl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		names := args
+		if len(names) == 0 {
+			var err error
+			if names, err = generatorTemplateNames(); err != nil {
+				fmt.Println("❌ Cannot list templates:", err)
+				return
+			}
+		}
+		for _, name := range names {
+			content, err := templatesFS.ReadFile(path.Join(generatorsDir, name))
+			if err != nil {
+				fmt.Println("❌ Unknown template:", name)
+				continue
+			}
+			target := filepath.Join(overrideDir, filepath.FromSlash(name))
+			if _, err := os.Stat(target); err == nil && !templatesEjectForce {
+				fmt.Println("ℹ️  Already ejected (use --force to overwrite):", target)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				fmt.Println("❌ Cannot create directory:", err)
+				return
+			}
+			if err := os.WriteFile(target, content, 0644); err != nil {
+				fmt.Println("❌ Cannot write template:", err)
+				return
+			}
+			fmt.Println("✅ Ejected:", target)
+		}
+	},
+}
+
+func init() {
+	templatesEjectCmd.Flags().BoolVarP(&templatesEjectForce, "force", "f", false, "Overwrite templates that were already ejected")
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesEjectCmd)
+	RootCmd.AddCommand(templatesCmd)
+}
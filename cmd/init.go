@@ -30,7 +30,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-//go:embed templates/*
+//go:embed templates/* generators/*
 var templatesFS embed.FS
 
 var initCmd = &cobra.Command{
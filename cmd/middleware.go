@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var generateMiddlewareCmd = &cobra.Command{
+	Use:   "middleware [name]",
+	Short: "Generate a new middleware and register it in ProviderSet and AppContainer",
+	Long: `Generate a new middleware in api/v1/middleware, register it in ProviderSet
+and expose it on AppContainer so routes can use it.
+
+Examples:
+  nvs generate middleware audit`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkName("middleware", args[0]); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		name := toPascalCase(args[0])
+		middlewareName := name + "Middleware"
+		fileName := filepath.Join("api", "v1", "middleware", toSnakeCase(name)+".go")
+		if _, err := os.Stat(fileName); err == nil {
+			fmt.Println("❌ Middleware file already exists:", fileName)
+			return
+		}
+
+		if err := checkAppContainerField("middleware", middlewareName); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		data := map[string]string{"Name": middlewareName}
+		if err := renderTemplateFile(fileName, "middleware/middleware.go.tmpl", data, true); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		fmt.Printf("✅ Created middleware: %s\n", fileName)
+
+		providersFile := filepath.Join("api", "v1", "middleware", "providers.go")
+		added, err := registerProvider(providersFile, "New"+middlewareName)
+		if err != nil {
+			fmt.Println("❌ Cannot update middleware providers.go:", err)
+			return
+		}
+
+		addedField := addToAppContainer("middleware", middlewareName)
+		if added || addedField {
+			runWire()
+		}
+	},
+}
+
+func init() {
+	generateCmd.AddCommand(generateMiddlewareCmd)
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateEnv   string
+	migrateDir   string
+	migrateTable string
+	migrateAll   bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Create, apply and roll back database migrations",
+	Long: `Create, apply and roll back the SQL migrations in migrations/.
+
+Migrations are NNNNNN_name.up.sql files with an optional NNNNNN_name.down.sql
+pair. The database is configured by the POSTGRES_* variables of config.yaml
+and the env files of --env (see nvs config print), and applied versions are
+tracked in the schema_migrations table. Every migration runs in its own
+transaction.`,
+}
+
+var migrateNewCmd = &cobra.Command{
+	Use:   "new [name]",
+	Short: "Create an empty up/down migration pair",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := toSnakeCase(args[0])
+		if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+			fmt.Println("❌ Invalid migration name:", args[0])
+			return
+		}
+		if err := os.MkdirAll(migrateDir, 0755); err != nil {
+			fmt.Println("❌ Cannot create migrations directory:", err)
+			return
+		}
+		version, err := nextMigrationVersion(migrateDir)
+		if err != nil {
+			fmt.Println("❌ Cannot read migrations directory:", err)
+			return
+		}
+		base := filepath.Join(migrateDir, fmt.Sprintf("%06d_%s", version, name))
+		files := []struct{ path, content string }{
+			{base + ".up.sql", "-- Apply " + name + "\n"},
+			{base + ".down.sql", "-- Revert " + name + "\n"},
+		}
+		for _, f := range files {
+			if err := os.WriteFile(f.path, []byte(f.content), 0644); err != nil {
+				fmt.Println("❌ Cannot create migration:", err)
+				return
+			}
+			fmt.Println("✅ Created:", f.path)
+		}
+	},
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up [N]",
+	Short: "Apply all pending migrations, or the next N",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, ok := migrateLimit(args, 0)
+		if !ok {
+			return
+		}
+		withMigrator(func(ctx context.Context, m *migrator) error {
+			count, err := m.up(ctx, limit, math.MaxInt64)
+			if err == nil && count == 0 {
+				fmt.Println("ℹ️  No pending migrations")
+			} else if err == nil {
+				fmt.Printf("✅ Applied %d migrations\n", count)
+			}
+			return err
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Roll back the latest migration, the latest N or --all",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, ok := migrateLimit(args, 1)
+		if !ok {
+			return
+		}
+		if migrateAll {
+			limit = 0
+		}
+		withMigrator(func(ctx context.Context, m *migrator) error {
+			count, err := m.down(ctx, limit, 0)
+			if err == nil && count == 0 {
+				fmt.Println("ℹ️  No applied migrations")
+			} else if err == nil {
+				fmt.Printf("✅ Rolled back %d migrations\n", count)
+			}
+			return err
+		})
+	},
+}
+
+var migrateGotoCmd = &cobra.Command{
+	Use:   "goto [version]",
+	Short: "Apply or roll back migrations until exactly those up to version are applied",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, ok := migrateVersion(args[0])
+		if !ok {
+			return
+		}
+		withMigrator(func(ctx context.Context, m *migrator) error {
+			if version != 0 && m.find(version) == nil {
+				return fmt.Errorf("migration %d not found in %s", version, migrateDir)
+			}
+			if _, err := m.down(ctx, 0, version); err != nil {
+				return err
+			}
+			if _, err := m.up(ctx, 0, version); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Database is at version %d\n", version)
+			return nil
+		})
+	},
+}
+
+var migrateForceCmd = &cobra.Command{
+	Use:   "force [version]",
+	Short: "Mark migrations up to version as applied without running them",
+	Long: `Mark the migrations up to version as applied, and later ones as not
+applied, without running any SQL. Use it to repair schema_migrations after
+changing the database by hand.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, ok := migrateVersion(args[0])
+		if !ok {
+			return
+		}
+		withMigrator(func(ctx context.Context, m *migrator) error {
+			if err := m.force(ctx, version); err != nil {
+				return err
+			}
+			fmt.Printf("✅ Forced version %d\n", version)
+			return nil
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		withMigrator(func(ctx context.Context, m *migrator) error {
+			applied, err := m.applied(ctx)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tDOWN")
+			for _, mig := range m.migrations {
+				status := "pending"
+				if a, ok := applied[mig.Version]; ok {
+					status = "applied " + a.AppliedAt.Local().Format("2006-01-02 15:04:05")
+					delete(applied, mig.Version)
+				}
+				down := "yes"
+				if mig.Down == "" {
+					down = "no"
+				}
+				fmt.Fprintf(w, "%06d\t%s\t%s\t%s\n", mig.Version, mig.Name, status, down)
+			}
+			for _, a := range applied {
+				fmt.Fprintf(w, "%06d\t%s\tapplied, file missing\t-\n", a.Version, a.Name)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+// withMigrator loads the project env, opens a migrator and runs fn,
+// exiting with status 1 on failure
+func withMigrator(fn func(ctx context.Context, m *migrator) error) {
+	if err := loadProjectEnv(migrateEnv); err != nil {
+		fmt.Println("⚠️", err)
+	}
+	dsn, err := postgresDSN()
+	if err != nil {
+		fmt.Println("❌ Cannot configure database:", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	m, err := newMigrator(ctx, dsn, migrateDir, migrateTable)
+	if err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+	err = fn(ctx, m)
+	m.close(ctx)
+	if err != nil {
+		fmt.Println("❌ Migration failed:", err)
+		os.Exit(1)
+	}
+}
+
+func migrateLimit(args []string, fallback int) (int, bool) {
+	if len(args) == 0 {
+		return fallback, true
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		fmt.Println("❌ N must be a positive number:", args[0])
+		return 0, false
+	}
+	return n, true
+}
+
+func migrateVersion(arg string) (int64, bool) {
+	version, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || version < 0 {
+		fmt.Println("❌ Invalid version:", arg)
+		return 0, false
+	}
+	return version, true
+}
+
+func init() {
+	migrateCmd.PersistentFlags().StringVarP(&migrateEnv, "env", "e", defaultProjectEnv(), "Environment whose configuration is loaded (dev, staging, prod)")
+	migrateCmd.PersistentFlags().StringVar(&migrateDir, "dir", "migrations", "Migrations directory")
+	migrateCmd.PersistentFlags().StringVar(&migrateTable, "table", "schema_migrations", "Table tracking applied versions")
+	migrateDownCmd.Flags().BoolVar(&migrateAll, "all", false, "Roll back every applied migration")
+
+	migrateCmd.AddCommand(migrateNewCmd)
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateGotoCmd)
+	migrateCmd.AddCommand(migrateForceCmd)
+	RootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var reMigrationFile = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+
+// migration is a version in the migrations directory
+type migration struct {
+	Version int64
+	Name    string
+	Up      string // path of the .up.sql file
+	Down    string // path of the .down.sql file, empty if missing
+}
+
+// appliedMigration is a row of the schema table
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// readMigrations returns the migrations in dir sorted by version
+func readMigrations(dir string) ([]*migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*migration{}
+	for _, e := range entries {
+		m := reMigrationFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s", e.Name())
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("version %d is used by %s and %s", version, mig.Name, m[2])
+		}
+		path := filepath.Join(dir, e.Name())
+		if m[3] == "up" {
+			mig.Up = path
+		} else {
+			mig.Down = path
+		}
+	}
+
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no .up.sql file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrator applies migrations to the project database and records them in table
+type migrator struct {
+	conn       *pgx.Conn
+	table      string
+	migrations []*migration
+}
+
+// newMigrator connects to the database, takes an advisory lock so concurrent
+// runs wait for each other and creates the schema table if needed
+func newMigrator(ctx context.Context, dsn, dir, table string) (*migrator, error) {
+	migrations, err := readMigrations(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations: %w", err)
+	}
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to database: %w", err)
+	}
+	m := &migrator{conn: conn, table: table, migrations: migrations}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", m.lockKey()); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("cannot lock %s: %w", table, err)
+	}
+	_, err = conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, m.quotedTable()))
+	if err != nil {
+		m.close(ctx)
+		return nil, fmt.Errorf("cannot create %s: %w", table, err)
+	}
+	return m, nil
+}
+
+func (m *migrator) close(ctx context.Context) {
+	m.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", m.lockKey())
+	m.conn.Close(ctx)
+}
+
+func (m *migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("nvs-migrate:" + m.table))
+	return int64(h.Sum64())
+}
+
+func (m *migrator) quotedTable() string {
+	return pgx.Identifier{m.table}.Sanitize()
+}
+
+// applied returns the recorded versions keyed by version
+func (m *migrator) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	rows, err := m.conn.Query(ctx, fmt.Sprintf("SELECT version, name, applied_at FROM %s", m.quotedTable()))
+	if err != nil {
+		return nil, err
+	}
+	applied, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (appliedMigration, error) {
+		var a appliedMigration
+		err := row.Scan(&a.Version, &a.Name, &a.AppliedAt)
+		return a, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]appliedMigration, len(applied))
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+	return byVersion, nil
+}
+
+// find returns the migration with version, or nil
+func (m *migrator) find(version int64) *migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+	return nil
+}
+
+// run executes the up or down file of mig and records the result in the same
+// transaction, so a failing migration leaves neither schema nor table changed
+func (m *migrator) run(ctx context.Context, mig *migration, up bool) error {
+	path := mig.Up
+	if !up {
+		path = mig.Down
+		if path == "" {
+			return fmt.Errorf("migration %d_%s has no .down.sql file", mig.Version, mig.Name)
+		}
+	}
+	sql, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, m.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if up {
+			_, err = tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2)", m.quotedTable()), mig.Version, mig.Name)
+		} else {
+			_, err = tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.quotedTable()), mig.Version)
+		}
+		return err
+	})
+}
+
+// up applies up to limit pending migrations (all when limit <= 0) with a
+// version not above target
+func (m *migrator) up(ctx context.Context, limit int, target int64) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	latest := int64(0)
+	for version := range applied {
+		if version > latest {
+			latest = version
+		}
+	}
+
+	count := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok || mig.Version > target {
+			continue
+		}
+		if limit > 0 && count >= limit {
+			break
+		}
+		if mig.Version < latest {
+			fmt.Printf("⚠️ %d_%s is older than the latest applied version %d\n", mig.Version, mig.Name, latest)
+		}
+		start := time.Now()
+		if err := m.run(ctx, mig, true); err != nil {
+			return count, err
+		}
+		count++
+		fmt.Printf("⬆️  %d_%s (%s)\n", mig.Version, mig.Name, time.Since(start).Round(time.Millisecond))
+	}
+	return count, nil
+}
+
+// down rolls back up to limit applied migrations (all when limit <= 0) with
+// a version above target, newest first
+func (m *migrator) down(ctx context.Context, limit int, target int64) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		if version > target {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	count := 0
+	for _, version := range versions {
+		if limit > 0 && count >= limit {
+			break
+		}
+		mig := m.find(version)
+		if mig == nil {
+			return count, fmt.Errorf("version %d (%s) is applied but its files are missing", version, applied[version].Name)
+		}
+		start := time.Now()
+		if err := m.run(ctx, mig, false); err != nil {
+			return count, err
+		}
+		count++
+		fmt.Printf("⬇️  %d_%s (%s)\n", mig.Version, mig.Name, time.Since(start).Round(time.Millisecond))
+	}
+	return count, nil
+}
+
+// force records exactly the migrations up to version as applied without
+// running any SQL, to repair the table after a manual change
+func (m *migrator) force(ctx context.Context, version int64) error {
+	return pgx.BeginFunc(ctx, m.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE version > $1", m.quotedTable()), version); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING", m.quotedTable()), mig.Version, mig.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMigrationFiles(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadMigrations(t *testing.T) {
+	dir := writeMigrationFiles(t,
+		"20250102000000_add_users.up.sql",
+		"20250102000000_add_users.down.sql",
+		"2_second.up.sql",
+		"0010_padded.up.sql",
+		"10000000000000000001_too_big.txt", // not a migration file
+		"README.md",
+		"1_first.up.sql",
+		"1_first.down.sql",
+	)
+	if err := os.Mkdir(filepath.Join(dir, "3_dir.up.sql"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := readMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		version int64
+		name    string
+		down    bool
+	}{
+		{1, "first", true},
+		{2, "second", false},
+		{10, "padded", false},
+		{20250102000000, "add_users", true},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("got %d migrations, want %d", len(migrations), len(want))
+	}
+	for i, w := range want {
+		m := migrations[i]
+		if m.Version != w.version || m.Name != w.name || (m.Down != "") != w.down {
+			t.Errorf("migration %d = %d_%s (down %q), want %d_%s (down %v)", i, m.Version, m.Name, m.Down, w.version, w.name, w.down)
+		}
+		if filepath.Dir(m.Up) != dir {
+			t.Errorf("migration %d up path = %s, want it in %s", i, m.Up, dir)
+		}
+	}
+}
+
+func TestReadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{"version overflow", []string{"99999999999999999999_huge.up.sql"}, "invalid migration version"},
+		{"duplicate version", []string{"1_first.up.sql", "1_other.up.sql"}, "version 1 is used by"},
+		{"down without up", []string{"1_first.down.sql"}, "has no .up.sql file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMigrations(writeMigrationFiles(t, tt.files...))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is the optional YAML file of a generated project
+const projectConfigFile = "config.yaml"
+
+// projectEnvFiles returns the env files a generated project reads for env,
+// lowest precedence first, the same way config.EnvFiles does
+func projectEnvFiles(env string) []string {
+	switch env {
+	case "dev":
+		return []string{".env.dev", ".env.local"}
+	case "prod":
+		return []string{".env", ".env.prod"}
+	}
+	return []string{".env." + env}
+}
+
+// defaultProjectEnv is the ENV variable of the shell, or dev
+func defaultProjectEnv() string {
+	if env := os.Getenv("ENV"); env != "" {
+		return env
+	}
+	return "dev"
+}
+
+// configLayer is one configuration source of a generated project
+type configLayer struct {
+	Name   string
+	Values map[string]string
+}
+
+// projectSources mirrors config.Sources of a generated project: config.yaml,
+// the env files, environment variables and flags, lowest precedence first
+type projectSources struct {
+	Env       string
+	EnvOrigin string
+	Layers    []configLayer
+}
+
+// resolveProjectSources reads the configuration sources of the project in the
+// current directory for env. flags are the project's command-line flags
+// (--port=8080, --redis-host cache).
+func resolveProjectSources(env, envOrigin, configFile string, flags []string) (*projectSources, error) {
+	s := &projectSources{Env: env, EnvOrigin: envOrigin}
+
+	values, err := readConfigYAML(configFile)
+	if err == nil {
+		if err := resolveProjectFileValues(configFile, values, nil); err != nil {
+			return nil, err
+		}
+		s.Layers = append(s.Layers, configLayer{Name: configFile, Values: values})
+	} else if !errors.Is(err, os.ErrNotExist) || configFile != projectConfigFile {
+		return nil, err
+	}
+
+	var key []byte
+	for _, file := range append(projectEnvFiles(env), projectSecretsFile(env)) {
+		values, err := godotenv.Read(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", file, err)
+		}
+		for name, value := range values {
+			if !strings.HasPrefix(value, encryptedPrefix) {
+				continue
+			}
+			if key == nil {
+				if key, err = loadSecretsKey(false); err != nil {
+					return nil, fmt.Errorf("cannot decrypt %s: %w", file, err)
+				}
+			}
+			if values[name], err = decryptSecret(key, name, value); err != nil {
+				return nil, fmt.Errorf("cannot decrypt %s: %w", file, err)
+			}
+		}
+		if err := resolveProjectFileValues(file, values, nil); err != nil {
+			return nil, err
+		}
+		s.Layers = append(s.Layers, configLayer{Name: file, Values: values})
+	}
+
+	environment := map[string]string{}
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			environment[key] = value
+		}
+	}
+	// Like the project, only declared keys are read from KEY_FILE variables
+	declared := map[string]bool{}
+	if fields, err := projectConfigFields("config"); err == nil {
+		for _, field := range fields {
+			declared[field.Key] = true
+		}
+	}
+	if err := resolveProjectFileValues("environment", environment, declared); err != nil {
+		return nil, err
+	}
+	s.Layers = append(s.Layers, configLayer{Name: "environment", Values: environment})
+
+	flagValues, err := parseConfigFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	s.Layers = append(s.Layers, configLayer{Name: "flags", Values: flagValues})
+	return s, nil
+}
+
+// resolveProjectFileValues replaces KEY_FILE entries with the contents of the
+// file they name, as config.resolveFileValues does. A KEY set in the same layer
+// wins; with only set, other keys are left alone.
+func resolveProjectFileValues(layer string, values map[string]string, only map[string]bool) error {
+	resolved := map[string]string{}
+	for name, path := range values {
+		key, ok := strings.CutSuffix(name, "_FILE")
+		if !ok || key == "" || path == "" || values[key] != "" || (only != nil && !only[key]) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", name, layer, err)
+		}
+		resolved[key] = strings.TrimRight(string(data), "\r\n")
+	}
+	for key, value := range resolved {
+		values[key] = value
+	}
+	return nil
+}
+
+// lookup returns the value of key and the layer it comes from
+func (s *projectSources) lookup(key string) (string, string, bool) {
+	if key == "ENV" {
+		return s.Env, s.EnvOrigin, true
+	}
+	for i := len(s.Layers) - 1; i >= 0; i-- {
+		if value := s.Layers[i].Values[key]; value != "" {
+			return value, s.Layers[i].Name, true
+		}
+	}
+	return "", "", false
+}
+
+// files returns the configuration files that were found
+func (s *projectSources) files() []string {
+	var files []string
+	for _, layer := range s.Layers {
+		if layer.Name != "environment" && layer.Name != "flags" {
+			files = append(files, layer.Name)
+		}
+	}
+	return files
+}
+
+// fileKeys returns the keys set by files and flags, sorted
+func (s *projectSources) fileKeys() []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, layer := range s.Layers {
+		if layer.Name == "environment" {
+			continue
+		}
+		for key := range layer.Values {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseConfigFlags turns --redis-host=cache or --redis-host cache into REDIS_HOST
+func parseConfigFlags(args []string) (map[string]string, error) {
+	values := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		if !strings.HasPrefix(args[i], "-") || name == "" {
+			return nil, fmt.Errorf("unexpected argument %q, project flags look like --port=8080", args[i])
+		}
+		name, value, ok := strings.Cut(name, "=")
+		if !ok {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag --%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "config" {
+			continue
+		}
+		values[strings.ToUpper(strings.ReplaceAll(name, "-", "_"))] = value
+	}
+	return values, nil
+}
+
+// readConfigYAML reads a YAML file into configuration keys the way the
+// project's config package does: nested keys are joined with "_" and
+// sequences become comma separated lists
+func readConfigYAML(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	values := map[string]string{}
+	if err := flattenConfigYAML("", doc, values); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flattenConfigYAML(prefix string, node map[string]interface{}, values map[string]string) error {
+	for name, value := range node {
+		key := prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		switch value := value.(type) {
+		case nil:
+		case map[string]interface{}:
+			if err := flattenConfigYAML(key+"_", value, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				switch item.(type) {
+				case map[string]interface{}, []interface{}:
+					return fmt.Errorf("%s: lists may only hold plain values", key)
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
+// loadProjectEnv loads config.yaml and the env files of env into the process
+// environment. Variables already exported in the shell take precedence.
+func loadProjectEnv(env string) error {
+	sources, err := resolveProjectSources(env, "flags", projectConfigFile, nil)
+	if err != nil {
+		return err
+	}
+	if len(sources.files()) == 0 {
+		return fmt.Errorf("no %s or %s found, using environment variables only", projectConfigFile, strings.Join(projectEnvFiles(env), "/"))
+	}
+	for _, key := range sources.fileKeys() {
+		if _, ok := os.LookupEnv(key); !ok {
+			value, _, _ := sources.lookup(key)
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// projectConfigField is a tagged field of the project's config.Config
+type projectConfigField struct {
+	Key      string
+	Default  string
+	Required bool
+	Secret   bool
+}
+
+// projectConfigFields reads the env, default, required and secret tags of
+// config.Config in dir, descending into nested structs of the package
+func projectConfigFields(dir string) ([]projectConfigField, error) {
+	fset := token.NewFileSet()
+	structs := map[string]*ast.StructType{}
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					structs[spec.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+	config, ok := structs["Config"]
+	if !ok {
+		return nil, fmt.Errorf("type Config not found in %s", dir)
+	}
+
+	var fields []projectConfigField
+	var walk func(st *ast.StructType, prefix string, depth int)
+	walk = func(st *ast.StructType, prefix string, depth int) {
+		for _, field := range st.Fields.List {
+			var tag reflect.StructTag
+			if field.Tag != nil {
+				unquoted, _ := strconv.Unquote(field.Tag.Value)
+				tag = reflect.StructTag(unquoted)
+			}
+			env, hasEnv := tag.Lookup("env")
+			if env == "-" {
+				continue
+			}
+			if ident, ok := field.Type.(*ast.Ident); ok && structs[ident.Name] != nil && depth < 10 {
+				walk(structs[ident.Name], prefix+env, depth+1)
+				continue
+			}
+			if !hasEnv {
+				continue
+			}
+			fields = append(fields, projectConfigField{
+				Key:      prefix + env,
+				Default:  tag.Get("default"),
+				Required: tag.Get("required") == "true",
+				Secret:   tag.Get("secret") == "true",
+			})
+		}
+	}
+	walk(config, "", 0)
+	return fields, nil
+}
+
+// isSecretKey reports whether key looks like it holds a credential
+func isSecretKey(key string) bool {
+	key = strings.ToUpper(key)
+	if strings.Contains(key, "PUBLIC") {
+		return false
+	}
+	for _, word := range []string{"PASSWORD", "SECRET", "TOKEN", "PRIVATE", "CREDENTIAL", "API_KEY"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, "_KEY")
+}
+
+// postgresDSN builds the connection string from the POSTGRES_* variables with
+// the same defaults as config.New() and db.GetPostgresURL()
+func postgresDSN() (string, error) {
+	var missing []string
+	get := func(key, fallback string, required bool) string {
+		value := os.Getenv(key)
+		if value == "" {
+			value = fallback
+		}
+		if value == "" && required {
+			missing = append(missing, key)
+		}
+		return value
+	}
+
+	host := get("POSTGRES_HOST", "", true)
+	port := get("POSTGRES_PORT", "5432", true)
+	user := get("POSTGRES_USER", "", true)
+	dbName := get("POSTGRES_DB", "", true)
+	password := get("POSTGRES_PASSWORD", "", false)
+	sslMode := get("POSTGRES_SSL_MODE", "disable", false)
+	rootCert := get("POSTGRES_ROOT_CERT_LOC", "", false)
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		dsnValue(host), dsnValue(port), dsnValue(user), dsnValue(password), dsnValue(dbName), dsnValue(sslMode))
+	if sslMode != "disable" && rootCert != "" {
+		dsn += " sslrootcert=" + dsnValue(rootCert)
+	}
+	return dsn, nil
+}
+
+// dsnValue quotes a keyword/value connection string value when needed
+func dsnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
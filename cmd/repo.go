@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var repoServices []string
+
+var sqlcCmd = &cobra.Command{
+	Use:   "sqlc",
+	Short: "Run sqlc generate and wire the repository into DI",
+	Long: `Run sqlc generate, then make sure api/v1/repositories exists and is part of
+the Wire graph, and regenerate di/wire_gen.go.
+
+The repository wraps models.New(tx) with the request transaction from
+handler.GetTrx, so every query a service runs through it joins that
+transaction.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSqlc(); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if _, err := os.Stat(filepath.Join("models", "querier.go")); err != nil {
+			fmt.Println("⚠️ models/querier.go not found, set emit_interface: true in sqlc.yaml")
+		}
+		changed, err := ensureRepository()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		injected, ok := injectRepository(repoServices)
+		if !ok {
+			return
+		}
+		if changed || injected {
+			runWire()
+		}
+	},
+}
+
+var generateRepoCmd = &cobra.Command{
+	Use:   "repo",
+	Short: "Generate the repository provider and inject it into services",
+	Long: `Generate api/v1/repositories with a Repository whose Queries(c) returns the
+sqlc Querier bound to the request transaction, register its ProviderSet in
+di/wire.go and optionally inject it into services.
+
+Examples:
+  nvs generate repo
+  nvs generate repo --service payment,order`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		changed, err := ensureRepository()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		injected, ok := injectRepository(repoServices)
+		if !ok {
+			return
+		}
+		if changed || injected {
+			runWire()
+		}
+	},
+}
+
+// runSqlc runs sqlc generate in the project root
+func runSqlc() error {
+	if _, err := exec.LookPath("sqlc"); err != nil {
+		return errors.New("sqlc not found, install it from https://docs.sqlc.dev/en/latest/overview/install.html")
+	}
+	sqlc := exec.Command("sqlc", "generate")
+	sqlc.Stdout = os.Stdout
+	sqlc.Stderr = os.Stderr
+	if err := sqlc.Run(); err != nil {
+		return fmt.Errorf("cannot run sqlc: %w", err)
+	}
+	fmt.Println("✅ sqlc generate completed")
+	return nil
+}
+
+// ensureRepository creates api/v1/repositories if missing and adds its
+// ProviderSet to wire.Build in di/wire.go. Returns true if anything changed.
+func ensureRepository() (bool, error) {
+	moduleName := readModuleName()
+	if moduleName == "" {
+		return false, errors.New("cannot read module name from go.mod")
+	}
+	dir := filepath.Join("api", "v1", "repositories")
+	changed := false
+	files := []struct{ path, tmpl string }{
+		{filepath.Join(dir, "repository.go"), "repository/repository.go.tmpl"},
+		{filepath.Join(dir, "providers.go"), "repository/providers.go.tmpl"},
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.path); err == nil {
+			continue
+		}
+		if err := renderTemplateFile(f.path, f.tmpl, map[string]string{"ModuleName": moduleName}, true); err != nil {
+			return false, err
+		}
+		fmt.Println("✅ Created:", f.path)
+		changed = true
+	}
+
+	wireFile := filepath.Join("di", "wire.go")
+	added, err := editGoFile(wireFile, func(s *goSource) (bool, error) {
+		importAdded, err := s.addImport(moduleName + "/api/v1/repositories")
+		if err != nil {
+			return false, err
+		}
+		argAdded, err := s.addCallArg("NewAppContainer", "wire.Build", "repositories.ProviderSet", "services.ProviderSet")
+		if err != nil {
+			return false, err
+		}
+		return importAdded || argAdded, nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("cannot update %s: %w", wireFile, err)
+	}
+	if added {
+		fmt.Println("✅ Added repositories.ProviderSet to wire.Build in di/wire.go")
+	}
+	return changed || added, nil
+}
+
+// injectRepository injects *repositories.Repository into the named services
+func injectRepository(services []string) (bool, bool) {
+	changed := false
+	for _, name := range services {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		injected, err := injectDependency(filepath.Join("api", "v1", "services"), toPascalCase(name)+"Service",
+			"Repository", "*repositories.Repository", readModuleName()+"/api/v1/repositories")
+		if err != nil {
+			fmt.Println("❌ Cannot inject repository:", err)
+			return changed, false
+		}
+		changed = changed || injected
+	}
+	return changed, true
+}
+
+func init() {
+	sqlcCmd.Flags().StringSliceVarP(&repoServices, "service", "s", nil, "Inject the repository into these services (e.g. payment,order)")
+	generateRepoCmd.Flags().StringSliceVarP(&repoServices, "service", "s", nil, "Inject the repository into these services (e.g. payment,order)")
+	generateCmd.AddCommand(generateRepoCmd)
+	RootCmd.AddCommand(sqlcCmd)
+}
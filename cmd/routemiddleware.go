@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// routeMiddlewareVar holds the container's BaseMiddleware in generated route files
+const routeMiddlewareVar = "mw"
+
+var (
+	reMiddlewareCall = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?:\((.*)\))?$`)
+	reIntLiteral     = regexp.MustCompile(`^-?[0-9]+$`)
+	reConstantName   = regexp.MustCompile(`^[A-Z][A-Za-z0-9_]*$`)
+	reQualifiedName  = regexp.MustCompile(`^(constants|time)\.[A-Z][A-Za-z0-9_]*$`)
+)
+
+// routeMiddleware knows which middleware the project offers to @Middleware and
+// @Auth annotations
+type routeMiddleware struct {
+	baseField   string          // AppContainer field of type *middleware.BaseMiddleware
+	baseMethods map[string]bool // methods of BaseMiddleware
+	fields      map[string]bool // AppContainer fields
+}
+
+// loadRouteMiddleware reads BaseMiddleware's methods and the AppContainer
+// fields of the project
+func loadRouteMiddleware() (*routeMiddleware, error) {
+	rm := &routeMiddleware{baseMethods: map[string]bool{}, fields: map[string]bool{}}
+
+	fset := token.NewFileSet()
+	files, _ := filepath.Glob(filepath.Join("api", "v1", "middleware", "*.go"))
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && receiverTypeName(fn) == "BaseMiddleware" {
+				rm.baseMethods[fn.Name.Name] = true
+			}
+		}
+	}
+
+	s, err := loadGoSource(filepath.Join("di", "wire.go"))
+	if err != nil {
+		return nil, err
+	}
+	st, err := s.structType("AppContainer")
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			rm.fields[name.Name] = true
+			if rm.baseField == "" && compact(s.text(field.Type)) == "*middleware.BaseMiddleware" {
+				rm.baseField = name.Name
+			}
+		}
+	}
+	return rm, nil
+}
+
+// resolve turns the annotations of every route in group into middleware
+// expressions, names the controller variable and returns the imports they need
+func (rm *routeMiddleware) resolve(group *controllerRoutes) (usesBase bool, imports []string, err error) {
+	needs := map[string]bool{}
+	for i := range group.Routes {
+		r := &group.Routes[i]
+		r.Middleware = nil
+		for _, a := range r.Annotations {
+			expr, base, err := rm.expr(a, needs)
+			if err != nil {
+				return false, nil, fmt.Errorf("%s: %w", a.Pos, err)
+			}
+			usesBase = usesBase || base
+			r.Middleware = append(r.Middleware, expr)
+		}
+	}
+	if usesBase && rm.baseField == "" {
+		return false, nil, fmt.Errorf("%s: AppContainer has no *middleware.BaseMiddleware field", group.File)
+	}
+	for imp := range needs {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	group.Var = routeVarName(group.Name, imports)
+	return usesBase, imports, nil
+}
+
+// expr converts one annotation into a fiber.Handler expression:
+//
+//	@Auth bearer                    -> mw.Auth("Bearer")
+//	@Middleware RateLimit(Tier4, 1m) -> mw.RateLimit(constants.Tier4, time.Minute)
+//	@Middleware Audit               -> c.AuditMiddleware.Handle()
+func (rm *routeMiddleware) expr(a routeAnnotation, needs map[string]bool) (string, bool, error) {
+	if a.Kind == "Auth" {
+		scheme := strings.TrimSpace(a.Value)
+		if strings.ContainsAny(scheme, " \t\"(),") {
+			return "", false, fmt.Errorf("invalid @Auth scheme %q", a.Value)
+		}
+		if !rm.baseMethods["Auth"] {
+			return "", false, fmt.Errorf("@Auth needs an Auth(scheme string) method on BaseMiddleware")
+		}
+		return fmt.Sprintf("%s.Auth(%q)", routeMiddlewareVar, title(scheme)), true, nil
+	}
+
+	m := reMiddlewareCall.FindStringSubmatch(strings.TrimSpace(a.Value))
+	if m == nil {
+		return "", false, fmt.Errorf("invalid @Middleware %q, expected Name or Name(args)", a.Value)
+	}
+	name, hasArgs := m[1], strings.Contains(a.Value, "(")
+	if !hasArgs {
+		field := toPascalCase(name) + "Middleware"
+		if !rm.fields[field] {
+			return "", false, fmt.Errorf("@Middleware %s: AppContainer has no %s field (run nvs generate middleware %s)", name, field, toSnakeCase(name))
+		}
+		return "c." + field + ".Handle()", false, nil
+	}
+	if !rm.baseMethods[name] {
+		return "", false, fmt.Errorf("@Middleware %s: BaseMiddleware has no %s method", a.Value, name)
+	}
+
+	var args []string
+	if strings.TrimSpace(m[2]) != "" {
+		raws, err := splitMiddlewareArgs(m[2])
+		if err != nil {
+			return "", false, fmt.Errorf("@Middleware %s: %w", a.Value, err)
+		}
+		for _, raw := range raws {
+			arg, err := middlewareArg(strings.TrimSpace(raw), needs)
+			if err != nil {
+				return "", false, fmt.Errorf("@Middleware %s: %w", a.Value, err)
+			}
+			args = append(args, arg)
+		}
+	}
+	return fmt.Sprintf("%s.%s(%s)", routeMiddlewareVar, name, strings.Join(args, ", ")), true, nil
+}
+
+// splitMiddlewareArgs splits an annotation argument list on the commas that
+// are outside of string literals ("a,b" stays one argument)
+func splitMiddlewareArgs(list string) ([]string, error) {
+	var args []string
+	start, inString := 0, false
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case inString && c == '\\':
+			i++ // skip the escaped character
+		case c == '"':
+			inString = !inString
+		case c == ',' && !inString:
+			args = append(args, list[start:i])
+			start = i + 1
+		}
+	}
+	if inString {
+		return nil, fmt.Errorf("unterminated string in %q", list)
+	}
+	return append(args, list[start:]), nil
+}
+
+// middlewareArg converts an annotation argument into Go: integers and strings
+// are kept, durations (1m, 30s) become time expressions and bare exported
+// names (Tier4) refer to the constants package
+func middlewareArg(arg string, needs map[string]bool) (string, error) {
+	switch {
+	case reIntLiteral.MatchString(arg):
+		return arg, nil
+	case len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"':
+		return arg, nil
+	case reQualifiedName.MatchString(arg):
+		needs[strings.SplitN(arg, ".", 2)[0]] = true
+		return arg, nil
+	case reConstantName.MatchString(arg):
+		needs["constants"] = true
+		return "constants." + arg, nil
+	}
+	if d, err := time.ParseDuration(arg); err == nil {
+		needs["time"] = true
+		return durationExpr(d), nil
+	}
+	return "", fmt.Errorf("unsupported argument %q", arg)
+}
+
+// durationExpr formats d with the largest time unit dividing it (90s -> 90 * time.Second)
+func durationExpr(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	if d == 0 {
+		return "0"
+	}
+	for _, u := range units {
+		if d%u.d == 0 {
+			if d == u.d {
+				return u.name
+			}
+			return fmt.Sprintf("%d * %s", d/u.d, u.name)
+		}
+	}
+	return fmt.Sprintf("%d", int64(d))
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareArg(t *testing.T) {
+	tests := []struct {
+		arg   string
+		want  string
+		needs string // package the argument needs, if any
+	}{
+		{"42", "42", ""},
+		{"-1", "-1", ""},
+		{`"a,b"`, `"a,b"`, ""},
+		{"Tier4", "constants.Tier4", "constants"},
+		{"constants.Tier4", "constants.Tier4", "constants"},
+		{"time.Minute", "time.Minute", "time"},
+		{"1m", "time.Minute", "time"},
+		{"90s", "90 * time.Second", "time"},
+	}
+	for _, tt := range tests {
+		needs := map[string]bool{}
+		got, err := middlewareArg(tt.arg, needs)
+		if err != nil {
+			t.Errorf("middlewareArg(%q) error: %v", tt.arg, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("middlewareArg(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+		if tt.needs != "" && !needs[tt.needs] || tt.needs == "" && len(needs) > 0 {
+			t.Errorf("middlewareArg(%q) needs %v, want %q", tt.arg, needs, tt.needs)
+		}
+	}
+
+	for _, arg := range []string{"tier4", "os.Exit", "1x", "a b"} {
+		if got, err := middlewareArg(arg, map[string]bool{}); err == nil {
+			t.Errorf("middlewareArg(%q) = %q, want an error", arg, got)
+		}
+	}
+}
+
+func TestDurationExpr(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0"},
+		{time.Hour, "time.Hour"},
+		{2 * time.Hour, "2 * time.Hour"},
+		{90 * time.Minute, "90 * time.Minute"},
+		{time.Minute, "time.Minute"},
+		{90 * time.Second, "90 * time.Second"},
+		{1500 * time.Millisecond, "1500 * time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+		{1500 * time.Nanosecond, "1500"},
+	}
+	for _, tt := range tests {
+		if got := durationExpr(tt.d); got != tt.want {
+			t.Errorf("durationExpr(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSplitMiddlewareArgs(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"Tier4, 1m", []string{"Tier4", " 1m"}},
+		{`"a,b", 1m`, []string{`"a,b"`, " 1m"}},
+		{`"say \"hi, there\"", 2`, []string{`"say \"hi, there\""`, " 2"}},
+		{`"a\\", "b"`, []string{`"a\\"`, ` "b"`}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		got, err := splitMiddlewareArgs(tt.list)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("splitMiddlewareArgs(%q) = %q, %v, want %q", tt.list, got, err, tt.want)
+		}
+	}
+	if _, err := splitMiddlewareArgs(`"a, 1m`); err == nil {
+		t.Error("unterminated string: want an error")
+	}
+}
+
+func TestRouteMiddlewareExpr(t *testing.T) {
+	rm := &routeMiddleware{
+		baseField:   "AuthMiddleware",
+		baseMethods: map[string]bool{"Auth": true, "RateLimit": true, "Tag": true},
+		fields:      map[string]bool{"AuditMiddleware": true},
+	}
+	tests := []struct {
+		kind, value string
+		want        string
+		usesBase    bool
+	}{
+		{"Auth", "bearer", `mw.Auth("Bearer")`, true},
+		{"Middleware", "RateLimit(Tier4, 1m)", "mw.RateLimit(constants.Tier4, time.Minute)", true},
+		{"Middleware", `Tag("a,b", 30s)`, `mw.Tag("a,b", 30 * time.Second)`, true},
+		{"Middleware", "Audit", "c.AuditMiddleware.Handle()", false},
+	}
+	for _, tt := range tests {
+		got, usesBase, err := rm.expr(routeAnnotation{Kind: tt.kind, Value: tt.value}, map[string]bool{})
+		if err != nil || got != tt.want || usesBase != tt.usesBase {
+			t.Errorf("expr(@%s %s) = %q, %v, %v, want %q, %v", tt.kind, tt.value, got, usesBase, err, tt.want, tt.usesBase)
+		}
+	}
+
+	for _, value := range []string{"Unknown(1)", "Missing", `Tag("open, 1)`} {
+		if got, _, err := rm.expr(routeAnnotation{Kind: "Middleware", Value: value}, map[string]bool{}); err == nil {
+			t.Errorf("expr(@Middleware %s) = %q, want an error", value, got)
+		}
+	}
+}
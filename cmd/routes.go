@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var routesCheck bool
+
+var routesCmd = &cobra.Command{
+	Use:   "routes",
+	Short: "List the registered routes and verify them against controller annotations",
+	Long: `List every route registered from SetupRoutes in api/v1/routes with its
+handler, middleware and the swag @Router annotation it came from.
+
+With --check the command exits with status 1 when:
+  - a controller @Router annotation has no registered route
+  - a registered route has no @Router annotation
+  - two routes share the same method and path
+  - a path repeats the ` + apiPrefix + ` prefix (e.g. ` + apiPrefix + apiPrefix + `/...)
+
+Examples:
+  nvs routes
+  nvs routes --check`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		routes, err := loadRouteTable(filepath.Join("api", "v1", "routes"))
+		if err != nil {
+			fmt.Println("❌ Cannot read routes:", err)
+			os.Exit(1)
+		}
+		groups, err := scanControllerRoutes(filepath.Join("api", "v1", "controllers"))
+		if err != nil {
+			fmt.Println("❌ Cannot read controllers:", err)
+			os.Exit(1)
+		}
+		problems := matchRouteAnnotations(routes, groups)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "METHOD\tPATH\tHANDLER\tMIDDLEWARE\t@ROUTER")
+		for _, r := range routes {
+			middleware := strings.Join(r.Middleware, ", ")
+			if middleware == "" {
+				middleware = "-"
+			}
+			source := r.Source
+			if source == "" {
+				source = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Method, r.Path, r.Handler, middleware, source)
+		}
+		w.Flush()
+
+		if !routesCheck {
+			return
+		}
+		fmt.Println()
+		if len(problems) == 0 {
+			fmt.Printf("✅ %d routes match their controller annotations\n", len(routes))
+			return
+		}
+		for _, p := range problems {
+			fmt.Println("❌", p)
+		}
+		fmt.Printf("\n%d problems found\n", len(problems))
+		os.Exit(1)
+	},
+}
+
+// registeredRoute is a route registered in api/v1/routes
+type registeredRoute struct {
+	Method     string   // upper case HTTP method
+	Path       string   // full path including group prefixes
+	Handler    string   // e.g. ProductController.Get
+	Middleware []string // handler arguments before the last one
+	Pos        string   // file:line of the registration
+	Source     string   // file:line of the matching @Router annotation
+}
+
+var reFiberParam = regexp.MustCompile(`:[A-Za-z0-9_]+\??|\*|\+`)
+
+// routeKey identifies a route independently of its parameter names
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + reFiberParam.ReplaceAllString(path, ":")
+}
+
+// loadRouteTable follows SetupRoutes through app.Group and RegisterXxxRoutes
+// calls and returns every route it registers, in registration order
+func loadRouteTable(dir string) ([]*registeredRoute, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	t := &routeTable{
+		fset:  token.NewFileSet(),
+		funcs: map[string]*ast.FuncDecl{},
+		types: map[string]string{},
+	}
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(t.fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				t.funcs[fn.Name.Name] = fn
+			}
+		}
+	}
+
+	// AppContainer field types name the controllers behind handler variables
+	if s, err := loadGoSource(filepath.Join("di", "wire.go")); err == nil {
+		if st, err := s.structType("AppContainer"); err == nil {
+			for _, field := range st.Fields.List {
+				typ := compact(s.text(field.Type))
+				typ = typ[strings.LastIndex(typ, ".")+1:]
+				for _, name := range field.Names {
+					t.types[name.Name] = typ
+				}
+			}
+		}
+	}
+
+	setup, ok := t.funcs["SetupRoutes"]
+	if !ok {
+		return nil, fmt.Errorf("SetupRoutes not found in %s", dir)
+	}
+	t.walk(setup, map[int]string{0: ""}, nil)
+	return t.routes, nil
+}
+
+// routeTable evaluates the route functions of the routes package
+type routeTable struct {
+	fset   *token.FileSet
+	funcs  map[string]*ast.FuncDecl
+	types  map[string]string // AppContainer field -> type name
+	routes []*registeredRoute
+}
+
+// walk records the routes registered by fn. prefixes maps the index of each
+// fiber.Router parameter to the path prefix of the router passed in.
+func (t *routeTable) walk(fn *ast.FuncDecl, prefixes map[int]string, stack []string) {
+	for _, name := range stack {
+		if name == fn.Name.Name {
+			return // recursive registration
+		}
+	}
+	stack = append(stack, fn.Name.Name)
+
+	routers := map[string]string{} // variable -> path prefix
+	controllers := map[string]string{}
+	i := 0
+	for _, field := range fn.Type.Params.List {
+		for _, name := range field.Names {
+			if prefix, ok := prefixes[i]; ok {
+				routers[name.Name] = prefix
+			}
+			i++
+		}
+	}
+	if fn.Body == nil {
+		return
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+				return true
+			}
+			lhs, ok := n.Lhs[0].(*ast.Ident)
+			if !ok {
+				return true
+			}
+			switch rhs := n.Rhs[0].(type) {
+			case *ast.CallExpr:
+				// v1API := app.Group("/api/v1")
+				if recv, method, ok := selectorCall(rhs); ok && method == "Group" {
+					if prefix, ok := routers[recv]; ok && len(rhs.Args) > 0 {
+						if path, ok := stringLit(rhs.Args[0]); ok {
+							routers[lhs.Name] = joinRoutePath(prefix, path)
+						}
+					}
+				}
+			case *ast.SelectorExpr:
+				// product := c.ProductController
+				controllers[lhs.Name] = rhs.Sel.Name
+			}
+
+		case *ast.CallExpr:
+			if recv, method, ok := selectorCall(n); ok {
+				prefix, isRouter := routers[recv]
+				if !isRouter || !isRouteMethod(strings.ToLower(method)) || len(n.Args) < 2 {
+					return true
+				}
+				path, ok := stringLit(n.Args[0])
+				if !ok {
+					return true
+				}
+				pos := t.fset.Position(n.Pos())
+				r := &registeredRoute{
+					Method:  strings.ToUpper(method),
+					Path:    joinRoutePath(prefix, path),
+					Handler: t.handlerName(n.Args[len(n.Args)-1], controllers),
+					Pos:     fmt.Sprintf("%s:%d", pos.Filename, pos.Line),
+				}
+				for _, arg := range n.Args[1 : len(n.Args)-1] {
+					r.Middleware = append(r.Middleware, t.nodeText(arg))
+				}
+				t.routes = append(t.routes, r)
+				return true
+			}
+
+			// RegisterProductRoutes(v1API, container)
+			ident, ok := n.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			callee, ok := t.funcs[ident.Name]
+			if !ok {
+				return true
+			}
+			args := map[int]string{}
+			for i, arg := range n.Args {
+				if v, ok := arg.(*ast.Ident); ok {
+					if prefix, ok := routers[v.Name]; ok {
+						args[i] = prefix
+					}
+				}
+			}
+			if len(args) > 0 {
+				t.walk(callee, args, stack)
+			}
+		}
+		return true
+	})
+}
+
+// handlerName returns Controller.Method for product.Get when product holds
+// c.ProductController, otherwise the expression as written
+func (t *routeTable) handlerName(expr ast.Expr, controllers map[string]string) string {
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		field := ""
+		switch x := sel.X.(type) {
+		case *ast.Ident:
+			field = controllers[x.Name]
+		case *ast.SelectorExpr:
+			// c.ProductController.Get
+			field = x.Sel.Name
+		}
+		if typ, ok := t.types[field]; ok {
+			return typ + "." + sel.Sel.Name
+		}
+		if field != "" {
+			return field + "." + sel.Sel.Name
+		}
+	}
+	return t.nodeText(expr)
+}
+
+func (t *routeTable) nodeText(n ast.Node) string {
+	var b strings.Builder
+	if err := format.Node(&b, t.fset, n); err != nil {
+		return "?"
+	}
+	return b.String()
+}
+
+// selectorCall returns recv and method of a recv.method(...) call
+func selectorCall(call *ast.CallExpr) (string, string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+	recv, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	return recv.Name, sel.Sel.Name, true
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// joinRoutePath joins a group prefix and a route path the way Fiber does
+func joinRoutePath(prefix, path string) string {
+	joined := strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+	if len(joined) > 1 {
+		joined = strings.TrimSuffix(joined, "/")
+	}
+	return joined
+}
+
+// matchRouteAnnotations links routes to their @Router annotations and returns
+// every inconsistency between the two
+func matchRouteAnnotations(routes []*registeredRoute, groups []*controllerRoutes) []string {
+	var problems []string
+
+	registered := map[string]*registeredRoute{}
+	for _, r := range routes {
+		if strings.HasPrefix(r.Path, apiPrefix+apiPrefix) {
+			problems = append(problems, fmt.Sprintf("%s: %s %s repeats the %s prefix", r.Pos, r.Method, r.Path, apiPrefix))
+		}
+		key := routeKey(r.Method, r.Path)
+		if other, ok := registered[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %s %s collides with the route registered at %s", r.Pos, r.Method, r.Path, other.Pos))
+			continue
+		}
+		registered[key] = r
+	}
+
+	annotated := map[string]bool{}
+	for _, group := range groups {
+		for _, a := range group.Routes {
+			path := rePathParam.ReplaceAllString(a.Path, ":$1")
+			key := routeKey(a.Method, path)
+			if annotated[key] {
+				problems = append(problems, fmt.Sprintf("%s: @Router %s [%s] is declared more than once", a.Pos, a.Path, strings.ToLower(a.Method)))
+				continue
+			}
+			annotated[key] = true
+
+			handler := group.Controller + "." + a.Handler
+			r, ok := registered[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: @Router %s [%s] of %s has no registered route", a.Pos, a.Path, strings.ToLower(a.Method), handler))
+				continue
+			}
+			r.Source = a.Pos
+			if r.Handler != handler {
+				problems = append(problems, fmt.Sprintf("%s: %s %s is handled by %s but annotated on %s", r.Pos, r.Method, r.Path, r.Handler, handler))
+			}
+		}
+	}
+
+	for _, r := range routes {
+		if r.Source == "" && !annotated[routeKey(r.Method, r.Path)] {
+			problems = append(problems, fmt.Sprintf("%s: %s %s (%s) has no @Router annotation", r.Pos, r.Method, r.Path, r.Handler))
+		}
+	}
+	return problems
+}
+
+func init() {
+	routesCmd.Flags().BoolVar(&routesCheck, "check", false, "Exit with status 1 when routes and controller annotations disagree")
+	RootCmd.AddCommand(routesCmd)
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// apiPrefix is the group SetupRoutes mounts generated routes on; swag @Router
+// paths include it, route files register paths relative to it
+const apiPrefix = "/api/v1"
+
+// routeMethods lists the HTTP methods a @Router line may use, in the order
+// routes for the same path are registered
+var routeMethods = []string{"get", "head", "post", "put", "patch", "delete", "options", "connect", "trace"}
+
+var (
+	reRouterLine = regexp.MustCompile(`^@Router\s+(\S+)\s+\[([A-Za-z]+)\]\s*$`)
+	rePathParam  = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+	// @Middleware and @Auth are nvs annotations, swag ignores them
+	reRouteAnnotation = regexp.MustCompile(`^@(Middleware|Auth)\s+(.+)$`)
+)
+
+// controllerRoute is one @Router line of a controller method
+type controllerRoute struct {
+	Method   string // Fiber method name (e.g. Get)
+	Path     string // swag path as written (e.g. /api/v1/products/{id})
+	Route    string // Fiber path relative to apiPrefix (e.g. /products/:id)
+	Handler  string // method name on the controller
+	Pos      string // file:line of the @Router comment
+	Prefixed bool   // Path starts with apiPrefix
+
+	// Annotations are the @Middleware and @Auth lines of the handler, in order
+	Annotations []routeAnnotation
+	// Middleware are the Go expressions resolved from Annotations
+	Middleware []string
+}
+
+// routeAnnotation is a @Middleware or @Auth line on a handler
+type routeAnnotation struct {
+	Kind  string // Middleware or Auth
+	Value string // e.g. RateLimit(Tier4, 1m) or bearer
+	Pos   string
+}
+
+// controllerRoutes holds the routes of one controller type
+type controllerRoutes struct {
+	Controller string // e.g. ProductController
+	Name       string // e.g. Product
+	Var        string // local variable holding the controller, set by routeMiddleware.resolve
+	Tag        string // first @Tags value
+	File       string
+	Routes     []controllerRoute
+}
+
+// scanControllerRoutes parses every controller in dir and collects the @Router
+// annotations of methods with a *fiber.Ctx parameter, grouped by controller
+// type. Controllers and routes are sorted so the result is stable across runs.
+func scanControllerRoutes(dir string) ([]*controllerRoutes, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	fset := token.NewFileSet()
+	byController := map[string]*controllerRoutes{}
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Doc == nil || !isFiberHandler(fn) {
+				continue
+			}
+			controller := receiverTypeName(fn)
+			if controller == "" {
+				continue
+			}
+
+			var tag string
+			var routes []controllerRoute
+			var annotations []routeAnnotation
+			for _, c := range fn.Doc.List {
+				line := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+				pos := fset.Position(c.Pos())
+				if tag == "" && strings.HasPrefix(line, "@Tags") {
+					tag = strings.TrimSpace(strings.Split(strings.TrimPrefix(line, "@Tags"), ",")[0])
+				}
+				if m := reRouteAnnotation.FindStringSubmatch(line); m != nil {
+					annotations = append(annotations, routeAnnotation{
+						Kind:  m[1],
+						Value: strings.TrimSpace(m[2]),
+						Pos:   fmt.Sprintf("%s:%d", pos.Filename, pos.Line),
+					})
+					continue
+				}
+				if !strings.HasPrefix(line, "@Router") {
+					continue
+				}
+				m := reRouterLine.FindStringSubmatch(line)
+				if m == nil {
+					return nil, fmt.Errorf("%s:%d: invalid @Router annotation %q", pos.Filename, pos.Line, line)
+				}
+				method := strings.ToLower(m[2])
+				if !isRouteMethod(method) {
+					return nil, fmt.Errorf("%s:%d: unsupported HTTP method %q", pos.Filename, pos.Line, m[2])
+				}
+				routes = append(routes, controllerRoute{
+					Method:   title(method),
+					Path:     m[1],
+					Route:    fiberRoutePath(m[1]),
+					Handler:  fn.Name.Name,
+					Pos:      fmt.Sprintf("%s:%d", pos.Filename, pos.Line),
+					Prefixed: hasAPIPrefix(m[1]),
+				})
+			}
+			if len(routes) == 0 {
+				continue
+			}
+			for i := range routes {
+				routes[i].Annotations = annotations
+			}
+
+			group, ok := byController[controller]
+			if !ok {
+				name := strings.TrimSuffix(controller, "Controller")
+				group = &controllerRoutes{
+					Controller: controller,
+					Name:       name,
+					File:       path,
+				}
+				byController[controller] = group
+			}
+			if group.Tag == "" {
+				group.Tag = tag
+			}
+			group.Routes = append(group.Routes, routes...)
+		}
+	}
+
+	groups := make([]*controllerRoutes, 0, len(byController))
+	for _, group := range byController {
+		sort.SliceStable(group.Routes, func(i, j int) bool {
+			return lessRoute(group.Routes[i], group.Routes[j])
+		})
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Controller < groups[j].Controller })
+	return groups, nil
+}
+
+// isFiberHandler reports whether fn has the func(*fiber.Ctx) error shape
+func isFiberHandler(fn *ast.FuncDecl) bool {
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Ctx"
+}
+
+// receiverTypeName returns the type name of fn's receiver (T or *T)
+func receiverTypeName(fn *ast.FuncDecl) string {
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func isRouteMethod(method string) bool {
+	for _, m := range routeMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAPIPrefix(path string) bool {
+	return path == apiPrefix || strings.HasPrefix(path, apiPrefix+"/")
+}
+
+// fiberRoutePath converts a swag path to a Fiber path relative to apiPrefix
+// (/api/v1/products/{id} -> /products/:id)
+func fiberRoutePath(path string) string {
+	if hasAPIPrefix(path) {
+		path = strings.TrimPrefix(path, apiPrefix)
+	}
+	path = rePathParam.ReplaceAllString(path, ":$1")
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+// lessRoute orders routes by path segment, placing static segments before
+// parameters so /products/search is registered ahead of /products/:id, then
+// by HTTP method
+func lessRoute(a, b controllerRoute) bool {
+	as := strings.Split(strings.Trim(a.Route, "/"), "/")
+	bs := strings.Split(strings.Trim(b.Route, "/"), "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		if ra, rb := segmentRank(as[i]), segmentRank(bs[i]); ra != rb {
+			return ra < rb
+		}
+		return as[i] < bs[i]
+	}
+	if len(as) != len(bs) {
+		return len(as) < len(bs)
+	}
+	if ma, mb := methodRank(a.Method), methodRank(b.Method); ma != mb {
+		return ma < mb
+	}
+	return a.Handler < b.Handler
+}
+
+func segmentRank(segment string) int {
+	switch {
+	case strings.HasPrefix(segment, "*"), strings.HasPrefix(segment, "+"):
+		return 2
+	case strings.HasPrefix(segment, ":"):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func methodRank(method string) int {
+	for i, m := range routeMethods {
+		if strings.EqualFold(m, method) {
+			return i
+		}
+	}
+	return len(routeMethods)
+}
+
+// routeVarName returns a local variable name for a controller that is not a
+// keyword and does not shadow a predeclared identifier, the parameters of
+// RegisterXxxRoutes or the packages the route file imports
+func routeVarName(name string, imports []string) string {
+	v := lowerFirst(name)
+	switch {
+	case v == "", v == "app", v == "c", v == routeMiddlewareVar, v == "di", v == "fiber",
+		token.IsKeyword(v), types.Universe.Lookup(v) != nil, slices.Contains(imports, v):
+		return v + "Controller"
+	}
+	return v
+}
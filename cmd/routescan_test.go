@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestLessRouteOrder(t *testing.T) {
+	want := []controllerRoute{
+		{Method: "Get", Route: "/products", Handler: "List"},
+		{Method: "Post", Route: "/products", Handler: "Create"},
+		{Method: "Get", Route: "/products/search", Handler: "Search"},
+		{Method: "Get", Route: "/products/:id", Handler: "Get"},
+		{Method: "Put", Route: "/products/:id", Handler: "Update"},
+		{Method: "Delete", Route: "/products/:id", Handler: "Delete"},
+		{Method: "Get", Route: "/products/:id/images", Handler: "Images"},
+		{Method: "Get", Route: "/products/*", Handler: "Fallback"},
+		{Method: "Get", Route: "/users", Handler: "Users"},
+	}
+	for seed := int64(0); seed < 10; seed++ {
+		got := slices.Clone(want)
+		rand.New(rand.NewSource(seed)).Shuffle(len(got), func(i, j int) { got[i], got[j] = got[j], got[i] })
+		sort.Slice(got, func(i, j int) bool { return lessRoute(got[i], got[j]) })
+		for i := range want {
+			if got[i].Handler != want[i].Handler {
+				t.Fatalf("seed %d: route %d is %s %s, want %s %s", seed, i, got[i].Method, got[i].Route, want[i].Method, want[i].Route)
+			}
+		}
+	}
+}
+
+func TestRouteVarName(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports []string
+		want    string
+	}{
+		{"Product", nil, "product"},
+		{"OrderItem", nil, "orderItem"},
+		{"App", nil, "appController"},
+		{"C", nil, "cController"},
+		{"Mw", nil, "mwController"},
+		{"Di", nil, "diController"},
+		{"Fiber", nil, "fiberController"},
+		{"Type", nil, "typeController"},
+		{"Func", nil, "funcController"},
+		{"String", nil, "stringController"},
+		{"Nil", nil, "nilController"},
+		{"Time", nil, "time"},
+		{"Time", []string{"time"}, "timeController"},
+		{"Constants", []string{"constants", "time"}, "constantsController"},
+	}
+	for _, tt := range tests {
+		if got := routeVarName(tt.name, tt.imports); got != tt.want {
+			t.Errorf("routeVarName(%q, %v) = %q, want %q", tt.name, tt.imports, got, tt.want)
+		}
+	}
+}
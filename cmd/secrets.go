@@ -0,0 +1,519 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+)
+
+// Secrets files are written in the format the project's config package reads
+// (see config/secrets.go): KEY=enc:v1:<base64 nonce and AES-256-GCM ciphertext>,
+// with the key name as additional data.
+
+const (
+	encryptedPrefix = "enc:v1:"
+	secretsKeyFile  = ".nvs/secrets.key"
+)
+
+var secretsEnv string
+
+var reSecretName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted env file of an environment",
+	Long: `Manage .env.<env>.enc, the encrypted env file the project decrypts in memory
+when it loads its configuration. Key names stay readable in the file and every
+value is encrypted with AES-256-GCM.
+
+The key is read from NVS_SECRETS_KEY (base64), the file named by
+NVS_SECRETS_KEY_FILE or .nvs/secrets.key. The first set or edit creates
+.nvs/secrets.key and adds it to .gitignore; share it through your secret store,
+never through git.`,
+}
+
+var secretsSetCmd = &cobra.Command{
+	Use:   "set [KEY] [value]",
+	Short: "Encrypt and store a value, read from stdin when omitted",
+	Long: `Encrypt and store a value in .env.<env>.enc. Without a value argument it is
+read from stdin, which keeps it out of the shell history.
+
+Examples:
+  nvs secrets set POSTGRES_PASSWORD --env prod
+  echo -n "$TOKEN" | nvs secrets set API_TOKEN -e staging`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if !reSecretName.MatchString(name) {
+			fmt.Println("❌ Invalid key:", name)
+			os.Exit(1)
+		}
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+				fmt.Printf("Value for %s: ", name)
+			}
+			data, err := readAllStdin()
+			if err != nil {
+				fmt.Println("❌ Cannot read value:", err)
+				os.Exit(1)
+			}
+			value = strings.TrimRight(data, "\r\n")
+		}
+
+		key, err := loadSecretsKey(true)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		file := projectSecretsFile(secretsEnv)
+		values, err := readSecretsFile(file)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if values[name], err = encryptSecret(key, name, value); err != nil {
+			fmt.Println("❌ Cannot encrypt:", err)
+			os.Exit(1)
+		}
+		if err := writeSecretsFile(file, secretsEnv, values); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Stored %s in %s\n", name, file)
+	},
+}
+
+var secretsGetCmd = &cobra.Command{
+	Use:   "get [KEY]",
+	Short: "Print a decrypted value, or the stored keys without KEY",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := projectSecretsFile(secretsEnv)
+		values, err := readSecretsFile(file)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if len(args) == 0 {
+			for _, name := range sortedKeys(values) {
+				fmt.Println(name)
+			}
+			return
+		}
+
+		value, ok := values[args[0]]
+		if !ok {
+			fmt.Printf("❌ %s is not set in %s\n", args[0], file)
+			os.Exit(1)
+		}
+		key, err := loadSecretsKey(false)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		plain, err := decryptSecret(key, args[0], value)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		fmt.Println(plain)
+	},
+}
+
+var secretsEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the decrypted values in $EDITOR and encrypt them again",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := loadSecretsKey(true)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		file := projectSecretsFile(secretsEnv)
+		values, err := readSecretsFile(file)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		plain, err := decryptSecrets(key, values)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+
+		// The plaintext only exists in a private temporary directory
+		dir, err := os.MkdirTemp("", "nvs-secrets-")
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		defer os.RemoveAll(dir)
+		tmp := filepath.Join(dir, filepath.Base(strings.TrimSuffix(file, ".enc")))
+		content := marshalSecrets(plain)
+		if parsed, err := godotenv.Unmarshal(content); err != nil || !equalValues(plain, parsed) {
+			fmt.Println("❌ Some values cannot be written as a .env file, use `nvs secrets set` for them")
+			os.Exit(1)
+		}
+		if err := os.WriteFile(tmp, []byte(content), 0600); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if err := runEditor(tmp); err != nil {
+			fmt.Println("❌ Editor failed, nothing changed:", err)
+			os.Exit(1)
+		}
+
+		edited, err := godotenv.Read(tmp)
+		if err != nil {
+			fmt.Println("❌ Cannot parse the edited values, nothing changed:", err)
+			os.Exit(1)
+		}
+		if equalValues(plain, edited) {
+			fmt.Println("ℹ️  No changes")
+			return
+		}
+		encrypted := map[string]string{}
+		for name, value := range edited {
+			if !reSecretName.MatchString(name) {
+				fmt.Println("❌ Invalid key, nothing changed:", name)
+				os.Exit(1)
+			}
+			// Unchanged values keep their ciphertext
+			if previous, ok := plain[name]; ok && previous == value {
+				encrypted[name] = values[name]
+				continue
+			}
+			if encrypted[name], err = encryptSecret(key, name, value); err != nil {
+				fmt.Println("❌ Cannot encrypt:", err)
+				os.Exit(1)
+			}
+		}
+		if err := writeSecretsFile(file, secretsEnv, encrypted); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Saved %d values in %s\n", len(encrypted), file)
+	},
+}
+
+var secretsRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Create a new key and encrypt every .env.*.enc file with it",
+	Long: `Create a new key and encrypt every .env.*.enc file of the project with it. The
+new key is written to .nvs/secrets.key (or NVS_SECRETS_KEY_FILE); deployments
+that set NVS_SECRETS_KEY need the new value before they load the new files.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		oldKey, err := loadSecretsKey(false)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		newKey := make([]byte, 32)
+		if _, err := rand.Read(newKey); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		files, err := filepath.Glob(".env.*.enc")
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+
+		// Encrypt everything before writing anything
+		rotated := map[string]map[string]string{}
+		for _, file := range files {
+			values, err := readSecretsFile(file)
+			if err != nil {
+				fmt.Println("❌", err)
+				os.Exit(1)
+			}
+			plain, err := decryptSecrets(oldKey, values)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", file, err)
+				os.Exit(1)
+			}
+			rotated[file] = map[string]string{}
+			for name, value := range plain {
+				if rotated[file][name], err = encryptSecret(newKey, name, value); err != nil {
+					fmt.Println("❌ Cannot encrypt:", err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		keyPath := secretsKeyPath()
+		if err := writeFileAtomic(keyPath+".new", []byte(base64.StdEncoding.EncodeToString(newKey)+"\n"), 0600); err != nil {
+			fmt.Println("❌ Cannot write the new key:", err)
+			os.Exit(1)
+		}
+		for _, file := range files {
+			env := strings.TrimSuffix(strings.TrimPrefix(file, ".env."), ".enc")
+			if err := writeSecretsFile(file, env, rotated[file]); err != nil {
+				fmt.Printf("❌ %v (the new key is in %s.new)\n", err, keyPath)
+				os.Exit(1)
+			}
+			fmt.Println("✅ Re-encrypted:", file)
+		}
+		if err := os.Rename(keyPath+".new", keyPath); err != nil {
+			fmt.Printf("❌ Cannot replace %s, the new key is in %s.new: %v\n", keyPath, keyPath, err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ New key written to", keyPath)
+		if os.Getenv("NVS_SECRETS_KEY") != "" {
+			fmt.Printf("⚠️ NVS_SECRETS_KEY is set, update it with the contents of %s\n", keyPath)
+		}
+	},
+}
+
+// projectSecretsFile returns the encrypted env file of env, as config.SecretsFile does
+func projectSecretsFile(env string) string {
+	return ".env." + env + ".enc"
+}
+
+// secretsKeyPath is the key file used when NVS_SECRETS_KEY is not set
+func secretsKeyPath() string {
+	if file := os.Getenv("NVS_SECRETS_KEY_FILE"); file != "" {
+		return file
+	}
+	return secretsKeyFile
+}
+
+// loadSecretsKey reads the key the way config.LoadSecretsKey does. With create
+// it writes a new .nvs/secrets.key when there is none and ignores it in git.
+func loadSecretsKey(create bool) ([]byte, error) {
+	if encoded := os.Getenv("NVS_SECRETS_KEY"); encoded != "" {
+		return decodeSecretsKey(encoded, "NVS_SECRETS_KEY")
+	}
+	path := secretsKeyPath()
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return decodeSecretsKey(string(data), path)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if !create {
+		return nil, fmt.Errorf("no secrets key: set NVS_SECRETS_KEY or NVS_SECRETS_KEY_FILE, or create %s with nvs secrets set", secretsKeyFile)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("cannot write %s: %w", path, err)
+	}
+	fmt.Printf("⚠️ Created %s, keep it out of version control and share it through your secret store\n", path)
+	if err := ignoreInGit(path); err != nil {
+		fmt.Println("⚠️ Cannot update .gitignore:", err)
+	}
+	return key, nil
+}
+
+func decodeSecretsKey(encoded, source string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s is not a base64 encoded 32 byte key", source)
+	}
+	return key, nil
+}
+
+// ignoreInGit adds path to .gitignore unless it is already listed
+func ignoreInGit(path string) error {
+	entry := filepath.ToSlash(path)
+	data, err := os.ReadFile(".gitignore")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == entry {
+			return nil
+		}
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, []byte(entry+"\n")...)
+	return os.WriteFile(".gitignore", data, 0644)
+}
+
+func secretsGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptSecret(key []byte, name, value string) (string, error) {
+	gcm, err := secretsGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSecret(key []byte, name, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil // written by hand, stored as is
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("%s: malformed encrypted value", name)
+	}
+	gcm, err := secretsGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("%s: malformed encrypted value", name)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("%s: cannot decrypt, wrong key or modified value", name)
+	}
+	return string(plain), nil
+}
+
+func decryptSecrets(key []byte, values map[string]string) (map[string]string, error) {
+	plain := make(map[string]string, len(values))
+	for name, value := range values {
+		var err error
+		if plain[name], err = decryptSecret(key, name, value); err != nil {
+			return nil, err
+		}
+	}
+	return plain, nil
+}
+
+// readSecretsFile returns the encrypted values of file, none if it is missing
+func readSecretsFile(file string) (map[string]string, error) {
+	values, err := godotenv.Read(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", file, err)
+	}
+	return values, nil
+}
+
+// writeSecretsFile writes encrypted values sorted by key
+func writeSecretsFile(file, env string, values map[string]string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Encrypted configuration of %s, change it with nvs secrets set|edit --env %s\n", env, env)
+	for _, name := range sortedKeys(values) {
+		fmt.Fprintf(&b, "%s=%s\n", name, values[name])
+	}
+	if err := writeFileAtomic(file, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("cannot write %s: %w", file, err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path so readers never see a partial file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runEditor opens path in $VISUAL or $EDITOR
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	parts := strings.Fields(editor)
+	edit := exec.Command(parts[0], append(parts[1:], path)...)
+	edit.Stdin = os.Stdin
+	edit.Stdout = os.Stdout
+	edit.Stderr = os.Stderr
+	return edit.Run()
+}
+
+func readAllStdin() (string, error) {
+	var b bytes.Buffer
+	_, err := b.ReadFrom(os.Stdin)
+	return b.String(), err
+}
+
+// marshalSecrets writes values as a .env file that godotenv reads back unchanged.
+// Every value is double quoted, so numbers like "007" keep their leading zeros.
+func marshalSecrets(values map[string]string) string {
+	var b strings.Builder
+	for _, name := range sortedKeys(values) {
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(secretsEscaper.Replace(values[name]))
+		b.WriteString("\"\n")
+	}
+	return b.String()
+}
+
+var secretsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalValues(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	secretsCmd.PersistentFlags().StringVarP(&secretsEnv, "env", "e", defaultProjectEnv(), "Environment of the secrets file (dev, staging, prod)")
+	secretsCmd.AddCommand(secretsSetCmd)
+	secretsCmd.AddCommand(secretsGetCmd)
+	secretsCmd.AddCommand(secretsEditCmd)
+	secretsCmd.AddCommand(secretsRotateCmd)
+	RootCmd.AddCommand(secretsCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/joho/godotenv"
+)
+
+func TestMarshalSecretsRoundTrip(t *testing.T) {
+	values := map[string]string{
+		"PIN":       "007",
+		"ZIP":       "01234",
+		"QUOTED":    `say "hi" twice`,
+		"BACKSLASH": `C:\path`,
+		"MULTILINE": "line 1\nline 2\r\n",
+		"DOLLAR":    "$HOME and ${PIN}",
+		"ESCAPED":   `\$PIN`,
+		"SPACES":    "  padded  ",
+		"HASH":      "#not a comment",
+		"EMPTY":     "",
+	}
+	content := marshalSecrets(values)
+	parsed, err := godotenv.Unmarshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalValues(values, parsed) {
+		for name, want := range values {
+			if parsed[name] != want {
+				t.Errorf("%s = %q, want %q", name, parsed[name], want)
+			}
+		}
+	}
+	if marshalSecrets(parsed) != content {
+		t.Error("marshalling the parsed values again changed the file")
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	serviceController string
+	serviceCache      bool
+)
+
+var generateServiceCmd = &cobra.Command{
+	Use:   "service [name]",
+	Short: "Generate a new service and register it in ProviderSet",
+	Long: `Generate a new service in api/v1/services and register it in ProviderSet.
+
+With --controller the service is also injected into that controller's struct
+and constructor. With --cache the typed cache (*cache.Cache) is injected into
+the service, for cache.GetOrLoad and friends.
+
+Examples:
+  nvs generate service payment
+  nvs generate service payment --controller order
+  nvs generate service payment --cache`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkName("service", args[0]); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if serviceController != "" {
+			if err := checkName("controller", serviceController); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+		}
+		name := toPascalCase(args[0])
+		serviceName := name + "Service"
+		fileName := filepath.Join("api", "v1", "services", toSnakeCase(name)+".go")
+		if _, err := os.Stat(fileName); err == nil {
+			if serviceController == "" && !serviceCache {
+				fmt.Println("❌ Service file already exists:", fileName)
+				return
+			}
+			fmt.Println("ℹ️  Service file already exists:", fileName)
+		} else {
+			data := map[string]string{"Name": serviceName}
+			if err := renderTemplateFile(fileName, "service/service.go.tmpl", data, true); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			fmt.Printf("✅ Created service: %s\n", fileName)
+		}
+
+		providersFile := filepath.Join("api", "v1", "services", "providers.go")
+		added, err := registerProvider(providersFile, "New"+serviceName)
+		if err != nil {
+			fmt.Println("❌ Cannot update services providers.go:", err)
+			return
+		}
+
+		if serviceCache {
+			injected, err := injectDependency(filepath.Join("api", "v1", "services"), serviceName,
+				"Cache", "*cache.Cache", readModuleName()+"/cache")
+			if err != nil {
+				fmt.Println("❌ Cannot inject cache:", err)
+				return
+			}
+			added = added || injected
+		}
+
+		if serviceController != "" {
+			injected, err := injectService(toPascalCase(serviceController)+"Controller", serviceName)
+			if err != nil {
+				fmt.Println("❌ Cannot inject service:", err)
+				return
+			}
+			added = added || injected
+		}
+
+		if added {
+			runWire()
+		}
+	},
+}
+
+// injectService adds a *services.<serviceName> field to the controller struct,
+// a matching constructor parameter and the assignment in the returned literal
+func injectService(controllerName, serviceName string) (bool, error) {
+	return injectDependency(filepath.Join("api", "v1", "controllers"), controllerName,
+		serviceName, "*services."+serviceName, readModuleName()+"/api/v1/services")
+}
+
+// injectDependency adds the field "fieldName typeExpr" to structName in dir,
+// the parameter lowerFirst(fieldName) to New<structName> and the assignment
+// in the literal it returns. Nothing is added when a field of that type exists.
+func injectDependency(dir, structName, fieldName, typeExpr, importPath string) (bool, error) {
+	path, err := findTypeFile(dir, structName)
+	if err != nil {
+		return false, err
+	}
+	param := lowerFirst(fieldName)
+
+	changed, err := editGoFile(path, func(s *goSource) (bool, error) {
+		importAdded, err := s.addImport(importPath)
+		if err != nil {
+			return false, err
+		}
+		st, err := s.structType(structName)
+		if err != nil {
+			return false, err
+		}
+		for _, field := range st.Fields.List {
+			if compact(s.text(field.Type)) == compact(typeExpr) {
+				// already injected, possibly under another name
+				return importAdded, nil
+			}
+		}
+		fieldAdded, err := s.addStructField(structName, fieldName, typeExpr)
+		if err != nil {
+			return false, err
+		}
+		paramAdded, err := s.addParam("New"+structName, param, typeExpr)
+		if err != nil {
+			return false, err
+		}
+		valueAdded := false
+		if paramAdded {
+			if valueAdded, err = s.addKeyValue("New"+structName, structName, fieldName, param); err != nil {
+				return false, err
+			}
+		}
+		return importAdded || fieldAdded || paramAdded || valueAdded, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		fmt.Printf("✅ Injected %s into %s (%s)\n", fieldName, structName, path)
+	} else {
+		fmt.Printf("ℹ️  %s already injected into %s\n", fieldName, structName)
+	}
+	return changed, nil
+}
+
+// Helper: lower the first letter of an identifier
+func lowerFirst(str string) string {
+	if str == "" {
+		return str
+	}
+	return strings.ToLower(str[:1]) + str[1:]
+}
+
+func init() {
+	generateServiceCmd.Flags().StringVarP(&serviceController, "controller", "c", "", "Inject the service into this controller (e.g. order)")
+	generateServiceCmd.Flags().BoolVar(&serviceCache, "cache", false, "Inject the typed cache (*cache.Cache) into the service")
+	generateCmd.AddCommand(generateServiceCmd)
+}
@@ -16,8 +16,12 @@
 package middleware
 
 import (
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/config"
 	"github.com/burapha44/example/constants"
 	"github.com/burapha44/example/handler"
 
@@ -25,27 +29,88 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
+// TokenVerifier checks the credentials of an Authorization header and returns
+// what handlers see in ctx.Locals(constants.AuthTokenKey), e.g. JWT claims
+type TokenVerifier func(ctx *fiber.Ctx, token string) (any, error)
+
 type BaseMiddleware struct {
+	Config      *config.Manager
+	Cache       *cache.Cache  // nil when the cache is disabled
+	VerifyToken TokenVerifier // nil rejects every request behind Auth
+}
+
+func NewBaseMiddleware(configs *config.Manager, responseCache *cache.Cache, verifyToken TokenVerifier) *BaseMiddleware {
+	return &BaseMiddleware{
+		Config:      configs,
+		Cache:       responseCache,
+		VerifyToken: verifyToken,
+	}
 }
 
-func NewBaseMiddleware() *BaseMiddleware {
-	return &BaseMiddleware{}
+// NewTokenVerifier provides BaseMiddleware.VerifyToken. It returns nil, so Auth
+// answers 401 until it is replaced by a real check (a JWT signature, an API
+// key lookup).
+func NewTokenVerifier(configs *config.Manager) TokenVerifier {
+	return nil
 }
 
+// RateLimit limits each IP to count requests per duration on every path. count
+// is usually a tier (constants.Tier4); RATE_LIMIT_TIERS changes the count of a
+// tier on reload, which restarts the counters of the affected limiters.
 func (mw *BaseMiddleware) RateLimit(count int, duration time.Duration) fiber.Handler {
 	if duration == 0 {
 		duration = time.Minute // Default to x requests per minute
 	}
-	return limiter.New(limiter.Config{
-		Max:        count,
-		Expiration: duration,
-		KeyGenerator: func(c *fiber.Ctx) string {
-			return c.IP() + "_" + c.Path() // Limit each IP to a unique request per path
-		},
-		LimitReached: func(ctx *fiber.Ctx) error {
-			return handler.BuildError(ctx, constants.TooManyRequestsCode, fiber.ErrTooManyRequests.Code, nil, true)
-		},
-		SkipFailedRequests:     false,
-		SkipSuccessfulRequests: false,
+	build := func(max int) fiber.Handler {
+		return limiter.New(limiter.Config{
+			Max:        max,
+			Expiration: duration,
+			KeyGenerator: func(c *fiber.Ctx) string {
+				return c.IP() + "_" + c.Path() // Limit each IP to a unique request per path
+			},
+			LimitReached: func(ctx *fiber.Ctx) error {
+				return handler.BuildError(ctx, constants.TooManyRequestsCode, fiber.ErrTooManyRequests.Code, nil, true)
+			},
+			SkipFailedRequests:     false,
+			SkipSuccessfulRequests: false,
+		})
+	}
+
+	var current atomic.Pointer[fiber.Handler]
+	limit := build(mw.Config.Current().RateLimit(count))
+	current.Store(&limit)
+	mw.Config.Subscribe(func(old, new *config.Config) {
+		if max := new.RateLimit(count); max != old.RateLimit(count) {
+			limit := build(max)
+			current.Store(&limit)
+		}
 	})
+
+	return func(c *fiber.Ctx) error {
+		return (*current.Load())(c)
+	}
+}
+
+// Auth rejects requests without an "Authorization: <scheme> <credentials>"
+// header whose credentials pass VerifyToken, and stores what VerifyToken
+// returned in ctx.Locals(constants.AuthTokenKey). Without a VerifyToken every
+// request is rejected.
+func (mw *BaseMiddleware) Auth(scheme string) fiber.Handler {
+	prefix := strings.ToLower(scheme) + " "
+	return func(ctx *fiber.Ctx) error {
+		header := ctx.Get(fiber.HeaderAuthorization)
+		if mw.VerifyToken == nil || len(header) <= len(prefix) || strings.ToLower(header[:len(prefix)]) != prefix {
+			return handler.BuildError(ctx, constants.UnauthorizedCode, fiber.StatusUnauthorized, nil, true)
+		}
+		token := strings.TrimSpace(header[len(prefix):])
+		if token == "" {
+			return handler.BuildError(ctx, constants.UnauthorizedCode, fiber.StatusUnauthorized, nil, true)
+		}
+		credentials, err := mw.VerifyToken(ctx, token)
+		if err != nil {
+			return handler.BuildError(ctx, constants.UnauthorizedCode, fiber.StatusUnauthorized, nil, true)
+		}
+		ctx.Locals(constants.AuthTokenKey, credentials)
+		return ctx.Next()
+	}
 }
@@ -0,0 +1,160 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/constants"
+)
+
+// cachedResponse is a response kept by ResponseCache
+type cachedResponse struct {
+	ContentType  string
+	CacheControl string
+	ETag         string
+	Body         []byte
+}
+
+// ResponseCache caches the 200 responses of GET and HEAD requests in the cache
+// store for ttl (CACHE_DEFAULT_TTL when 0), per path, query and language, under
+// tags so handler.InvalidateCacheTags can drop them. Responses get a strong
+// ETag and conditional requests with a matching If-None-Match are answered
+// with 304 Not Modified, from the cache or after running the handler.
+//
+// Cache-Control is honoured both ways: requests with no-store bypass the
+// cache and requests with no-cache skip the cached copy, while responses with
+// no-store, private or a Set-Cookie header are not kept. Responses without a
+// Cache-Control header get "no-cache", so clients revalidate with their ETag
+// and an invalidation is visible right away. With the cache disabled only the
+// ETags and 304 answers remain.
+//
+// Requests with an Authorization header or a session cookie are personal and
+// pass through untouched, so a route behind Auth or a session is never served
+// from, or stored in, the shared cache.
+func (mw *BaseMiddleware) ResponseCache(ttl time.Duration, tags ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if ctx.Method() != fiber.MethodGet && ctx.Method() != fiber.MethodHead {
+			return ctx.Next()
+		}
+		if ctx.Get(fiber.HeaderAuthorization) != "" || ctx.Cookies(constants.SessionCookie) != "" {
+			return ctx.Next()
+		}
+		requestDirectives := ctx.Get(fiber.HeaderCacheControl)
+		if hasDirective(requestDirectives, "no-store") {
+			return ctx.Next()
+		}
+
+		key := responseCacheKey(ctx)
+		if !hasDirective(requestDirectives, "no-cache") {
+			cached, ok, err := cache.Get[cachedResponse](ctx.UserContext(), mw.Cache, key)
+			if err == nil && ok {
+				ctx.Set("X-Cache", "HIT")
+				return sendCachedResponse(ctx, cached)
+			}
+		}
+
+		if err := ctx.Next(); err != nil {
+			return err
+		}
+		response := ctx.Response()
+		if response.StatusCode() != fiber.StatusOK {
+			return nil
+		}
+		cached := cachedResponse{
+			ContentType:  string(response.Header.ContentType()),
+			CacheControl: string(response.Header.Peek(fiber.HeaderCacheControl)),
+			ETag:         string(response.Header.Peek(fiber.HeaderETag)),
+			Body:         slices.Clone(response.Body()),
+		}
+		if cached.CacheControl == "" {
+			cached.CacheControl = "no-cache"
+		}
+		if cached.ETag == "" {
+			sum := sha256.Sum256(cached.Body)
+			cached.ETag = `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`
+		}
+		// HEAD responses may leave the body out, so only GET fills the cache
+		if ctx.Method() == fiber.MethodGet && !hasDirective(cached.CacheControl, "no-store") &&
+			!hasDirective(cached.CacheControl, "private") && len(response.Header.Peek(fiber.HeaderSetCookie)) == 0 {
+			// A failing cache only costs the next request a handler run
+			_ = cache.Set(ctx.UserContext(), mw.Cache, key, cached, ttl, tags...)
+		}
+		ctx.Set("X-Cache", "MISS")
+		return sendCachedResponse(ctx, cached)
+	}
+}
+
+// responseCacheKey is the path, the query with its parameters sorted and the
+// language of the request
+func responseCacheKey(ctx *fiber.Ctx) string {
+	var params []string
+	ctx.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		params = append(params, string(key)+"="+string(value))
+	})
+	slices.Sort(params)
+	lang, _ := ctx.Locals(constants.LanguageKey).(string)
+	return "http:" + lang + ":" + ctx.Path() + "?" + strings.Join(params, "&")
+}
+
+// sendCachedResponse writes cached, or 304 Not Modified when the request
+// already has its ETag. The body depends on the language and the encoding
+// of the request, so shared caches have to vary on both.
+func sendCachedResponse(ctx *fiber.Ctx, cached cachedResponse) error {
+	ctx.Vary(fiber.HeaderAcceptLanguage, fiber.HeaderAcceptEncoding)
+	ctx.Set(fiber.HeaderETag, cached.ETag)
+	ctx.Set(fiber.HeaderCacheControl, cached.CacheControl)
+	if etagMatches(ctx.Get(fiber.HeaderIfNoneMatch), cached.ETag) {
+		ctx.Response().ResetBody()
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+	ctx.Set(fiber.HeaderContentType, cached.ContentType)
+	return ctx.Status(fiber.StatusOK).Send(cached.Body)
+}
+
+// etagMatches compares the If-None-Match list with etag, weakly as RFC 9110
+// asks for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirective reports whether the Cache-Control value contains directive
+func hasDirective(cacheControl, directive string) bool {
+	for _, part := range strings.Split(cacheControl, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(name, directive) {
+			return true
+		}
+	}
+	return false
+}
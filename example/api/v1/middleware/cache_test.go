@@ -0,0 +1,129 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/config"
+	"github.com/burapha44/example/constants"
+)
+
+func TestEtagMatches(t *testing.T) {
+	const etag = `"abc"`
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`"xyz",W/"abc"`, true},
+		{"*", true},
+		{`"xyz"`, false},
+		{`abc`, false},
+		{`"abcd"`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifNoneMatch, etag, got, tt.want)
+		}
+	}
+	if !etagMatches(`"abc"`, `W/"abc"`) {
+		t.Error("a weak ETag should match its strong form")
+	}
+}
+
+func TestHasDirective(t *testing.T) {
+	tests := []struct {
+		cacheControl, directive string
+		want                    bool
+	}{
+		{"no-store", "no-store", true},
+		{"max-age=60, No-Cache", "no-cache", true},
+		{"private=\"Set-Cookie\"", "private", true},
+		{"max-age=60", "no-cache", false},
+		{"no-cache-ish", "no-cache", false},
+		{"", "no-store", false},
+	}
+	for _, tt := range tests {
+		if got := hasDirective(tt.cacheControl, tt.directive); got != tt.want {
+			t.Errorf("hasDirective(%q, %q) = %v, want %v", tt.cacheControl, tt.directive, got, tt.want)
+		}
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	responseCache, err := cache.NewCache(cache.NewMemoryStore(100), &config.Config{ServiceName: "test", CacheCodec: "json", CacheDefaultTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw := NewBaseMiddleware(nil, responseCache, nil)
+	calls := 0
+	app := fiber.New()
+	app.Get("/items", mw.ResponseCache(0), func(ctx *fiber.Ctx) error {
+		calls++
+		return ctx.SendString(strconv.Itoa(calls))
+	})
+
+	tests := []struct {
+		name           string
+		header, value  string
+		wantBody       string
+		wantCache      string
+		wantStatusCode int
+	}{
+		{"first request", "", "", "1", "MISS", fiber.StatusOK},
+		{"cached", "", "", "1", "HIT", fiber.StatusOK},
+		{"authorization bypasses", fiber.HeaderAuthorization, "Bearer a", "2", "", fiber.StatusOK},
+		{"session cookie bypasses", fiber.HeaderCookie, constants.SessionCookie + "=s1", "3", "", fiber.StatusOK},
+		{"no-cache revalidates", fiber.HeaderCacheControl, "no-cache", "4", "MISS", fiber.StatusOK},
+		{"still cached", "", "", "4", "HIT", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodGet, "/items", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		res, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != tt.wantStatusCode || string(body) != tt.wantBody || res.Header.Get("X-Cache") != tt.wantCache {
+			t.Errorf("%s: got %d %q X-Cache %q, want %d %q X-Cache %q", tt.name,
+				res.StatusCode, body, res.Header.Get("X-Cache"), tt.wantStatusCode, tt.wantBody, tt.wantCache)
+		}
+		if tt.wantCache != "" && res.Header.Get(fiber.HeaderVary) != "Accept-Language, Accept-Encoding" {
+			t.Errorf("%s: Vary = %q", tt.name, res.Header.Get(fiber.HeaderVary))
+		}
+	}
+
+	req := httptest.NewRequest(fiber.MethodGet, "/items", nil)
+	res, _ := app.Test(req)
+	req.Header.Set(fiber.HeaderIfNoneMatch, res.Header.Get(fiber.HeaderETag))
+	if res, _ = app.Test(req); res.StatusCode != fiber.StatusNotModified {
+		t.Errorf("If-None-Match with the ETag: status %d, want 304", res.StatusCode)
+	}
+}
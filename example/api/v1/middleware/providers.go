@@ -22,4 +22,5 @@ import (
 // ProviderSet รวม Providers ของ Controllers
 var ProviderSet = wire.NewSet(
 	NewBaseMiddleware,
+	NewTokenVerifier,
 )
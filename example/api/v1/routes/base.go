@@ -16,23 +16,12 @@
 package routes
 
 import (
-	"log"
-
 	"github.com/burapha44/example/constants"
 	"github.com/burapha44/example/di"
 	"github.com/burapha44/example/handler"
 	"github.com/gofiber/fiber/v2"
 )
 
-func InitDIContainer() *di.AppContainer {
-	container, err := di.NewAppContainer()
-	if err != nil {
-		log.Panicf("❌ Failed to initialize DI Container: %v", err)
-	}
-	log.Println("✅ DI Container initialized successfully")
-	return container
-}
-
 func SetupRoutes(app *fiber.App, container *di.AppContainer) {
 	v1API := app.Group("/api/v1")
 
@@ -17,11 +17,13 @@ package routes
 
 import (
 	"github.com/burapha44/example/di"
+
 	"github.com/gofiber/fiber/v2"
 )
 
-func RegisterProductRoutes(app fiber.Router, c *di.AppContainer) {
+// Code generated by nvs generate routes from ProductController annotations.
 
-	Product := c.ProductController
-	app.Get("/api/v1/ProductController/info", Product.Example)
+func RegisterProductRoutes(app fiber.Router, c *di.AppContainer) {
+	product := c.ProductController
+	app.Get("/ProductController/info", product.Example)
 }
@@ -2,10 +2,13 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/capability"
 	"github.com/burapha44/example/config"
 	"github.com/burapha44/example/db"
 
@@ -14,11 +17,19 @@ import (
 
 type BaseService struct {
 	InitializedAt time.Time
+	Config        *config.Config
+	Capabilities  *capability.Registry
+	DB            *db.DB      // nil when the database is disabled
+	Cache         cache.Store // nil when the cache is disabled
 }
 
-func NewBaseService() *BaseService {
+func NewBaseService(conf *config.Config, capabilities *capability.Registry, database *db.DB, cacheStore cache.Store) *BaseService {
 	return &BaseService{
 		InitializedAt: time.Now(),
+		Config:        conf,
+		Capabilities:  capabilities,
+		DB:            database,
+		Cache:         cacheStore,
 	}
 }
 
@@ -44,54 +55,91 @@ func (s *BaseService) ServerInfo(c *fiber.Ctx) ServerInfoResponse {
 	hostname, _ := os.Hostname()
 	uptime := time.Since(s.InitializedAt).String()
 
-	dbStatus := DependencyStatus{Status: "DOWN", Message: "Not configured"}
-	if config.Conf.PostgresUser != "" && db.PostgresConn != nil {
-		ctx, cancel := context.WithTimeout(c.UserContext(), 5*time.Second)
-		defer cancel()
-		start := time.Now()
-		if err := db.PostgresConn.Ping(ctx); err != nil {
-			dbStatus = DependencyStatus{Status: "DOWN", Message: err.Error()}
-		} else {
-			dbStatus = DependencyStatus{Status: "UP", Message: "Connected", ResponseTimeMs: int(time.Since(start).Milliseconds())}
+	// Disabled subsystems are reported but never degrade the status
+	overallStatus, message := "UP", "All systems operational"
+	dependencies := map[string]DependencyStatus{}
+	for _, capa := range s.Capabilities.All() {
+		status := DependencyStatus{Status: "DISABLED", Message: capa.Reason}
+		if capa.Enabled {
+			switch capa.Name {
+			case capability.Database:
+				status = s.pingDatabase(c.UserContext())
+			case capability.Cache:
+				status = s.pingCache(c.UserContext())
+			default:
+				status = DependencyStatus{Status: "UP", Message: capa.Reason}
+			}
 		}
-	} else if config.Conf.PostgresUser != "" {
-		dbStatus = DependencyStatus{Status: "N/A", Message: "Database disabled by config"}
-	}
+		dependencies[string(capa.Name)] = status
 
-	redisStatus := DependencyStatus{Status: "DOWN", Message: "Not configured"}
-	if config.Conf.RedisHost != "" && cache.GetCacheClient() != nil {
-		ctx, cancel := context.WithTimeout(c.UserContext(), 2*time.Second)
-		defer cancel()
+		if status.Status == "DOWN" && capa.Name == capability.Database {
+			overallStatus, message = "DOWN", "Database is unavailable"
+		} else if status.Status == "DOWN" && overallStatus == "UP" {
+			overallStatus, message = "DEGRADED", "Some dependencies are unavailable"
+		}
+	}
 
-		start := time.Now()
-		if err := cache.GetCacheClient().Ping(ctx).Err(); err != nil {
-			redisStatus = DependencyStatus{Status: "DOWN", Message: err.Error()}
-		} else {
-			redisStatus = DependencyStatus{Status: "UP", Message: "Connected", ResponseTimeMs: int(time.Since(start).Milliseconds())}
+	// Ejected replicas only degrade the status, reads fall back to the primary
+	if replicas := s.DB.Replicas(); len(replicas) > 0 && s.Capabilities.Enabled(capability.Database) {
+		status := replicaStatus(replicas)
+		dependencies["database_replicas"] = status
+		if status.Status == "DOWN" && overallStatus == "UP" {
+			overallStatus, message = "DEGRADED", "Some dependencies are unavailable"
 		}
-	} else if config.Conf.RedisHost != "" {
-		redisStatus = DependencyStatus{Status: "N/A", Message: "Cache disabled by config"}
 	}
 
-	overallStatus := "UP"
-	if dbStatus.Status != "UP" || redisStatus.Status != "UP" {
-		overallStatus = "DEGRADED"
+	return ServerInfoResponse{
+		Status:       overallStatus,
+		Message:      message,
+		Timestamp:    time.Now().UTC(),
+		Version:      s.Config.Version,
+		ServiceName:  s.Config.ServiceName,
+		Environment:  s.Config.Environment,
+		Hostname:     hostname,
+		Uptime:       uptime,
+		Dependencies: dependencies,
 	}
-	if dbStatus.Status == "DOWN" && config.Conf.PostgresUser != "" {
-		overallStatus = "DOWN"
+}
+
+func (s *BaseService) pingDatabase(ctx context.Context) DependencyStatus {
+	if s.DB == nil {
+		return DependencyStatus{Status: "DOWN", Message: "Not connected"}
 	}
-	return ServerInfoResponse{
-		Status:      overallStatus,
-		Message:     "All systems operational",
-		Timestamp:   time.Now().UTC(),
-		Version:     config.Conf.Version,
-		ServiceName: config.Conf.ServiceName,
-		Environment: config.Conf.Environment,
-		Hostname:    hostname,
-		Uptime:      uptime,
-		Dependencies: map[string]DependencyStatus{
-			"database": dbStatus,
-			"redis":    redisStatus,
-		},
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.DB.Pool().Ping(ctx); err != nil {
+		return DependencyStatus{Status: "DOWN", Message: err.Error()}
+	}
+	return DependencyStatus{Status: "UP", Message: "Connected", ResponseTimeMs: int(time.Since(start).Milliseconds())}
+}
+
+// replicaStatus is DOWN when a replica is ejected, listing the replicas and their lag
+func replicaStatus(replicas []db.ReplicaStatus) DependencyStatus {
+	status := "UP"
+	parts := make([]string, 0, len(replicas))
+	for _, r := range replicas {
+		if !r.Healthy {
+			status = "DOWN"
+			parts = append(parts, fmt.Sprintf("%s ejected (%s)", r.Name, r.Error))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s lag %s", r.Name, r.Lag.Round(time.Millisecond)))
+	}
+	return DependencyStatus{Status: status, Message: strings.Join(parts, ", ")}
+}
+
+func (s *BaseService) pingCache(ctx context.Context) DependencyStatus {
+	if s.Cache == nil {
+		return DependencyStatus{Status: "DOWN", Message: "Not connected"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := s.Cache.Ping(ctx); err != nil {
+		return DependencyStatus{Status: "DOWN", Message: err.Error()}
 	}
+	return DependencyStatus{Status: "UP", Message: "Connected (" + s.Config.CacheDriver + ")", ResponseTimeMs: int(time.Since(start).Milliseconds())}
 }
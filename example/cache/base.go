@@ -23,17 +23,20 @@ import (
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/burapha44/example/capability"
 	"github.com/burapha44/example/config"
 )
 
-var (
-	client *redis.Client
-)
-
-func Init() error {
-	client = redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", config.Conf.RedisHost, config.Conf.RedisPort),
-		Password:     config.Conf.RedisPassword,
+// New connects to the Redis server configured by conf. It returns a nil
+// client unless the cache capability is enabled with CACHE_DRIVER=redis; the
+// cleanup function closes the client.
+func New(conf *config.Config, capabilities *capability.Registry) (*redis.Client, func(), error) {
+	if !capabilities.Enabled(capability.Cache) || conf.CacheDriver != "redis" {
+		return nil, func() {}, nil
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", conf.RedisHost, conf.RedisPort),
+		Password:     conf.RedisPassword,
 		DB:           0,
 		PoolSize:     10,
 		MinIdleConns: 5,
@@ -44,21 +47,11 @@ func Init() error {
 
 	// ทดสอบการเชื่อมต่อ
 	if err := client.Ping(ctx).Err(); err != nil {
-		log.Fatalf("Redis connection failed: %v", err)
-		return err
+		_ = client.Close()
+		return nil, nil, fmt.Errorf("redis connection failed: %w", err)
 	}
 
 	log.Println("💎 Redis connected successfully")
 
-	return nil
-}
-
-func GetCacheClient() *redis.Client {
-	return client
-}
-
-func Close() {
-	if client != nil {
-		_ = client.Close()
-	}
+	return client, func() { _ = client.Close() }, nil
 }
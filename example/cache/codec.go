@@ -0,0 +1,59 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec turns cached values into the bytes stored in Redis and back
+type Codec interface {
+	Name() string
+	Marshal(value any) ([]byte, error)
+	Unmarshal(data []byte, value any) error
+}
+
+var (
+	// JSON stores values as JSON, readable with redis-cli and other languages
+	JSON Codec = jsonCodec{}
+	// Msgpack stores values as MessagePack, smaller and faster to decode than JSON
+	Msgpack Codec = msgpackCodec{}
+)
+
+// CodecFor returns the codec named by CACHE_CODEC
+func CodecFor(name string) (Codec, error) {
+	for _, codec := range []Codec{JSON, Msgpack} {
+		if codec.Name() == name {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cache codec %q", name)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                           { return "json" }
+func (jsonCodec) Marshal(value any) ([]byte, error)      { return json.Marshal(value) }
+func (jsonCodec) Unmarshal(data []byte, value any) error { return json.Unmarshal(data, value) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string                           { return "msgpack" }
+func (msgpackCodec) Marshal(value any) ([]byte, error)      { return msgpack.Marshal(value) }
+func (msgpackCodec) Unmarshal(data []byte, value any) error { return msgpack.Unmarshal(data, value) }
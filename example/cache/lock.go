@@ -0,0 +1,254 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/burapha44/example/capability"
+)
+
+var (
+	// ErrLockHeld is returned by TryLock when another owner holds the lock
+	ErrLockHeld = errors.New("lock is held by another owner")
+	// ErrLockLost is returned by Release when the lock expired before it, so
+	// another owner may have run in the meantime
+	ErrLockLost = errors.New("lock was lost before it was released")
+)
+
+// Lock is a distributed lock held by this instance. It is renewed every third
+// of its ttl until Release, so it outlives slow work but expires soon after
+// the instance dies. Work done under the lock should use Context, which is
+// cancelled before the lock can expire unrenewed, and pass Token to the
+// resources it writes.
+type Lock struct {
+	store Store
+	key   string
+	owner string
+	token int64
+	ttl   time.Duration
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	renewed chan struct{} // closed when the renewal goroutine returns
+
+	mu   sync.Mutex
+	lost bool
+}
+
+// Lock waits until it holds the lock name, retrying every tenth of ttl, or
+// returns ctx.Err(). Locks are shared by every instance of SERVICE_NAME.
+func (c *Cache) Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	for {
+		lock, err := c.TryLock(ctx, name, ttl)
+		if !errors.Is(err, ErrLockHeld) {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(ttl / 10):
+		}
+	}
+}
+
+// TryLock takes the lock name for ttl, or returns ErrLockHeld when another
+// owner holds it. With the cache disabled it returns a capability.DisabledError.
+func (c *Cache) TryLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	if c == nil {
+		// NewCache only returns a nil Cache when the cache capability is disabled
+		return nil, &capability.DisabledError{Name: capability.Cache, Reason: "CACHE_DRIVER=none"}
+	}
+	if ttl < time.Millisecond {
+		return nil, fmt.Errorf("lock %s: ttl must be at least 1ms", name)
+	}
+	owner, err := newLockOwner()
+	if err != nil {
+		return nil, err
+	}
+	key := c.namespace + "locks:" + name
+	sentAt := time.Now()
+	token, err := c.store.AcquireLock(ctx, key, owner, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", name, err)
+	}
+	if token == 0 {
+		return nil, ErrLockHeld
+	}
+
+	lock := &Lock{store: c.store, key: key, owner: owner, token: token, ttl: ttl, renewed: make(chan struct{})}
+	// The lock outlives the request that took it, only losing it cancels its context
+	lock.ctx, lock.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	go lock.renew(sentAt)
+	return lock, nil
+}
+
+// Token is the fencing token of the lock: it grows with every acquisition, so
+// a resource that remembers the highest token it saw can reject the writes of
+// an owner whose lock expired while it was paused
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Context is cancelled when the lock is lost or released
+func (l *Lock) Context() context.Context {
+	return l.ctx
+}
+
+// Release stops the renewal and frees the lock if this owner still holds it.
+// It returns ErrLockLost when the lock had expired.
+func (l *Lock) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.renewed
+	l.mu.Lock()
+	lost := l.lost
+	l.mu.Unlock()
+	if lost {
+		return ErrLockLost
+	}
+	released, err := l.store.ReleaseLock(ctx, l.key, l.owner)
+	if err != nil {
+		return fmt.Errorf("release lock %s: %w", l.key, err)
+	}
+	if !released {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// renew extends the lock every third of its ttl. The store may have expired
+// the lock a ttl after the last successful extension was sent, so the lock is
+// treated as lost a tenth of the ttl before that, or as soon as another owner
+// holds it. Store errors are retried until then, and no extension may run past
+// it.
+func (l *Lock) renew(sentAt time.Time) {
+	defer close(l.renewed)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	validUntil := l.validUntil(sentAt)
+	expired := time.NewTimer(time.Until(validUntil))
+	defer expired.Stop()
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-expired.C:
+			l.lose()
+			return
+		case <-ticker.C:
+		}
+		sentAt := time.Now()
+		ctx, cancel := context.WithTimeout(l.ctx, min(l.ttl/3, time.Until(validUntil)))
+		extended, err := l.store.ExtendLock(ctx, l.key, l.owner, l.ttl)
+		cancel()
+		if l.ctx.Err() != nil {
+			return // released while extending
+		}
+		if err == nil && extended {
+			validUntil = l.validUntil(sentAt)
+			expired.Reset(time.Until(validUntil))
+			continue
+		}
+		if err != nil && time.Now().Before(validUntil) {
+			log.Printf("⚠️ Cannot renew lock %s, retrying: %v", l.key, err)
+			continue
+		}
+		l.lose()
+		return
+	}
+}
+
+// validUntil is when a lock extended by a request sent at sentAt may expire,
+// less a tenth of the ttl for the clock drift between this instance and the
+// store
+func (l *Lock) validUntil(sentAt time.Time) time.Time {
+	return sentAt.Add(l.ttl - l.ttl/10)
+}
+
+// lose marks the lock lost and cancels its context
+func (l *Lock) lose() {
+	log.Printf("⚠️ Lost lock %s", l.key)
+	l.mu.Lock()
+	l.lost = true
+	l.mu.Unlock()
+	l.cancel()
+}
+
+func newLockOwner() (string, error) {
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return "", fmt.Errorf("lock owner: %w", err)
+	}
+	return hex.EncodeToString(owner), nil
+}
+
+// Lead campaigns for the leadership of name among the instances of the service
+// until ctx is done, and returns ctx.Err() then. Each time this instance wins,
+// run is called with a context that is cancelled when the leadership is lost
+// or ctx is done; run should return then. When run returns the leadership is
+// given up and this instance waits a third of ttl before campaigning again, so
+// another instance can take over. ttl bounds how long the instances go without
+// a leader after the leader dies.
+func (c *Cache) Lead(ctx context.Context, name string, ttl time.Duration, run func(ctx context.Context) error) error {
+	for {
+		lock, err := c.Lock(ctx, name, ttl)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var disabled *capability.DisabledError
+			if errors.As(err, &disabled) {
+				return err
+			}
+			log.Printf("⚠️ Cannot campaign for %s: %v", name, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(ttl / 3):
+			}
+			continue
+		}
+
+		log.Printf("👑 Leading %s (token %d)", name, lock.Token())
+		leaderCtx, stop := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-lock.Context().Done():
+			case <-leaderCtx.Done():
+			}
+			stop()
+		}()
+		if err := run(leaderCtx); err != nil && leaderCtx.Err() == nil {
+			log.Printf("⚠️ Leader of %s failed: %v", name, err)
+		}
+		stop()
+		if err := lock.Release(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, ErrLockLost) {
+			log.Printf("⚠️ %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ttl / 3):
+		}
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store bounded to a number of entries: beyond
+// it the least recently used entry is evicted. Expired entries are dropped
+// when they are read or evicted, and leave their tags with them. Entries are
+// lost on restart and not shared between instances, which suits local
+// development and tests. Locks are kept apart from the entries and are never
+// evicted, they only lock out the goroutines of this process.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	recent     *list.List                     // front is the most recently used
+	tags       map[string]map[string]struct{} // tag to the keys added to it
+	locks      map[string]memoryLock
+	fences     map[string]int64 // last fencing token of each lock
+}
+
+type memoryLock struct {
+	owner     string
+	expiresAt time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // zero when the entry does not expire
+	tags      []string
+}
+
+// NewMemoryStore returns an empty MemoryStore holding up to maxEntries entries
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: max(maxEntries, 1),
+		entries:    map[string]*list.Element{},
+		recent:     list.New(),
+		tags:       map[string]map[string]struct{}{},
+		locks:      map[string]memoryLock{},
+		fences:     map[string]int64{},
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		s.remove(element)
+		return nil, false, nil
+	}
+	s.recent.MoveToFront(element)
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	// Callers may reuse the slice after Set returns
+	value = append([]byte(nil), value...)
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Like Redis, a key stays in the tags it was added to before
+	entry := &memoryEntry{key: key}
+	if element, ok := s.entries[key]; ok {
+		entry = element.Value.(*memoryEntry)
+		s.recent.MoveToFront(element)
+	} else {
+		s.entries[key] = s.recent.PushFront(entry)
+	}
+	entry.value, entry.expiresAt = value, expiresAt
+	for _, tag := range tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			s.tags[tag] = keys
+		}
+		if _, ok := keys[key]; !ok {
+			keys[key] = struct{}{}
+			entry.tags = append(entry.tags, tag)
+		}
+	}
+	for s.recent.Len() > s.maxEntries {
+		s.remove(s.recent.Back())
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if element, ok := s.entries[key]; ok {
+			s.remove(element)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) InvalidateTags(_ context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if element, ok := s.entries[key]; ok {
+				s.remove(element)
+			}
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+func (s *MemoryStore) AcquireLock(_ context.Context, lock, owner string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if held, ok := s.locks[lock]; ok && time.Now().Before(held.expiresAt) {
+		return 0, nil
+	}
+	s.locks[lock] = memoryLock{owner: owner, expiresAt: time.Now().Add(ttl)}
+	s.fences[lock]++
+	return s.fences[lock], nil
+}
+
+func (s *MemoryStore) ExtendLock(_ context.Context, lock, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held, ok := s.locks[lock]
+	if !ok || held.owner != owner || !time.Now().Before(held.expiresAt) {
+		return false, nil
+	}
+	s.locks[lock] = memoryLock{owner: owner, expiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) ReleaseLock(_ context.Context, lock, owner string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held, ok := s.locks[lock]
+	if !ok || held.owner != owner || !time.Now().Before(held.expiresAt) {
+		return false, nil
+	}
+	delete(s.locks, lock)
+	return true, nil
+}
+
+func (s *MemoryStore) Ping(context.Context) error {
+	return nil
+}
+
+// Len returns the number of entries, expired ones included until they are dropped
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recent.Len()
+}
+
+func (s *MemoryStore) remove(element *list.Element) {
+	entry := element.Value.(*memoryEntry)
+	s.recent.Remove(element)
+	delete(s.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(s.tags[tag], entry.key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(3)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := s.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading a and overwriting b makes c the least recently used
+	if _, ok, _ := s.Get(ctx, "a"); !ok {
+		t.Fatal("a missing")
+	}
+	_ = s.Set(ctx, "b", []byte("b2"), 0)
+	_ = s.Set(ctx, "d", []byte("d"), 0)
+
+	tests := []struct {
+		key  string
+		want string // empty when evicted
+	}{
+		{"a", "a"},
+		{"b", "b2"},
+		{"c", ""},
+		{"d", "d"},
+	}
+	for _, tt := range tests {
+		value, ok, err := s.Get(ctx, tt.key)
+		if err != nil || ok != (tt.want != "") || string(value) != tt.want {
+			t.Errorf("Get(%s) = %q, %v, %v, want %q", tt.key, value, ok, err, tt.want)
+		}
+	}
+	if s.Len() != 3 {
+		t.Errorf("Len = %d, want 3", s.Len())
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	_ = s.Set(ctx, "short", []byte("v"), time.Millisecond)
+	_ = s.Set(ctx, "forever", []byte("v"), 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok, _ := s.Get(ctx, "short"); ok {
+		t.Error("expired entry returned")
+	}
+	if _, ok, _ := s.Get(ctx, "forever"); !ok {
+		t.Error("entry without ttl expired")
+	}
+	if s.Len() != 1 {
+		t.Errorf("Len = %d, want 1 after the expired entry was read", s.Len())
+	}
+}
+
+func TestMemoryStoreGetReturnsCopy(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	value := []byte("value")
+	_ = s.Set(ctx, "k", value, 0)
+	value[0] = 'X'
+	got, _, _ := s.Get(ctx, "k")
+	got[1] = 'X'
+	if again, _, _ := s.Get(ctx, "k"); string(again) != "value" {
+		t.Errorf("stored value changed to %q", again)
+	}
+}
+
+func TestMemoryStoreTags(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	_ = s.Set(ctx, "p1", []byte("v"), 0, "products", "product:1")
+	_ = s.Set(ctx, "p2", []byte("v"), 0, "products")
+	_ = s.Set(ctx, "u1", []byte("v"), 0, "users")
+	// Like Redis, a key stays in the tags it was added to before
+	_ = s.Set(ctx, "p2", []byte("v2"), 0, "product:2")
+
+	if err := s.InvalidateTags(ctx, "product:2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.Get(ctx, "p2"); ok {
+		t.Error("p2 survived the invalidation of product:2")
+	}
+	if _, ok, _ := s.Get(ctx, "p1"); !ok {
+		t.Error("p1 was invalidated with product:2")
+	}
+
+	_ = s.InvalidateTags(ctx, "products", "unknown")
+	for key, want := range map[string]bool{"p1": false, "u1": true} {
+		if _, ok, _ := s.Get(ctx, key); ok != want {
+			t.Errorf("after invalidating products, Get(%s) ok = %v, want %v", key, ok, want)
+		}
+	}
+	if len(s.tags) != 1 || len(s.tags["users"]) != 1 {
+		t.Errorf("tags = %v, want only users", s.tags)
+	}
+}
+
+func TestMemoryStoreRemovalCleansTags(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		remove func(s *MemoryStore)
+	}{
+		{"delete", func(s *MemoryStore) { _ = s.Delete(ctx, "a") }},
+		{"eviction", func(s *MemoryStore) { _ = s.Set(ctx, "b", []byte("v"), 0) }},
+		{"expiry", func(s *MemoryStore) {
+			time.Sleep(5 * time.Millisecond)
+			_, _, _ = s.Get(ctx, "a")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore(1)
+			_ = s.Set(ctx, "a", []byte("v"), time.Millisecond, "t1", "t2")
+			tt.remove(s)
+			if _, ok := s.entries["a"]; ok {
+				t.Fatal("a is still stored")
+			}
+			if len(s.tags) != 0 {
+				t.Errorf("tags = %v, want none", s.tags)
+			}
+		})
+	}
+}
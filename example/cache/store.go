@@ -0,0 +1,206 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/burapha44/example/config"
+)
+
+// Store keeps raw cache entries. Cache encodes values on top of it and the
+// session manager stores sessions in it, so neither depends on Redis. Keys
+// and tags are used as given, namespacing is left to the caller.
+type Store interface {
+	// Get returns the value of key; the bool is false when the key is missing
+	// or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key and adds key to tags, ttl 0 keeps it until it
+	// is evicted or deleted. A tag lives as long as its longest-lived key.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// Delete removes keys, missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+	// InvalidateTags deletes every key added to tags, and the tags
+	InvalidateTags(ctx context.Context, tags ...string) error
+
+	// AcquireLock sets lock to owner for ttl unless another owner holds it.
+	// It returns the fencing token of the acquisition, which grows with every
+	// acquisition of lock, or 0 when the lock is held.
+	AcquireLock(ctx context.Context, lock, owner string, ttl time.Duration) (int64, error)
+	// ExtendLock resets the ttl of lock if owner still holds it
+	ExtendLock(ctx context.Context, lock, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLock frees lock if owner still holds it
+	ReleaseLock(ctx context.Context, lock, owner string) (bool, error)
+	// Ping reports whether the store can be reached
+	Ping(ctx context.Context) error
+}
+
+// NewStore returns the store selected by CACHE_DRIVER: client, the Redis
+// client of New, for redis and a MemoryStore of CACHE_MEMORY_MAX_ENTRIES
+// entries for memory. It returns nil when the cache capability is disabled.
+func NewStore(client *redis.Client, conf *config.Config) (Store, error) {
+	switch conf.CacheDriver {
+	case "redis":
+		if client == nil {
+			return nil, errors.New("CACHE_DRIVER=redis but Redis is not connected")
+		}
+		return NewRedisStore(client), nil
+	case "memory":
+		return NewMemoryStore(conf.CacheMemoryMaxEntries), nil
+	default:
+		return nil, nil
+	}
+}
+
+// RedisStore is the Store of a Redis server. A tag is a Redis set of keys,
+// under the tag name.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore returns a Store that keeps entries in the database of client
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Client returns the Redis client, for commands the Store does not cover
+func (s *RedisStore) Client() *redis.Client {
+	return s.client
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// setTaggedScript sets KEYS[1] to ARGV[1] for ARGV[2] milliseconds (0 for no
+// expiry) and adds it to the tag sets KEYS[2..], extending their expiry to the
+// longest-lived member, atomically so a key is never cached untagged
+var setTaggedScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+for i = 2, #KEYS do
+	local existed = redis.call('EXISTS', KEYS[i]) == 1
+	redis.call('SADD', KEYS[i], KEYS[1])
+	if ttl == 0 then
+		redis.call('PERSIST', KEYS[i])
+	else
+		local current = redis.call('PTTL', KEYS[i])
+		if not existed or (current >= 0 and current < ttl) then
+			redis.call('PEXPIRE', KEYS[i], ttl)
+		end
+	end
+end
+if ttl == 0 then
+	return redis.call('SET', KEYS[1], ARGV[1])
+end
+return redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+`)
+
+// invalidateTagsScript deletes the members of the tag sets KEYS and the sets,
+// in batches to stay below the argument limit of unpack
+var invalidateTagsScript = redis.NewScript(`
+for _, tag in ipairs(KEYS) do
+	local keys = redis.call('SMEMBERS', tag)
+	for i = 1, #keys, 1000 do
+		redis.call('DEL', unpack(keys, i, math.min(i + 999, #keys)))
+	end
+	redis.call('DEL', tag)
+end
+return 0
+`)
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	// Redis counts in milliseconds and 0 means no expiry
+	if ttl > 0 && ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+	if len(tags) == 0 {
+		return s.client.Set(ctx, key, value, ttl).Err()
+	}
+	keys := append([]string{key}, tags...)
+	return setTaggedScript.Run(ctx, s.client, keys, value, ttl.Milliseconds()).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func (s *RedisStore) InvalidateTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	return invalidateTagsScript.Run(ctx, s.client, tags).Err()
+}
+
+// acquireLockScript sets KEYS[1] to the owner ARGV[1] for ARGV[2] milliseconds
+// if it is free and returns the next fencing token from KEYS[2], or 0
+var acquireLockScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+// extendLockScript resets the expiry of KEYS[1] to ARGV[2] milliseconds if the
+// owner ARGV[1] holds it
+var extendLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLockScript deletes KEYS[1] if the owner ARGV[1] holds it, so a lock
+// that expired and was taken over is left to its new owner
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// AcquireLock keeps the fencing counter of lock in "<lock>:fence", without
+// expiry so tokens keep growing after the lock expires
+func (s *RedisStore) AcquireLock(ctx context.Context, lock, owner string, ttl time.Duration) (int64, error) {
+	return acquireLockScript.Run(ctx, s.client, []string{lock, lock + ":fence"}, owner, ttl.Milliseconds()).Int64()
+}
+
+func (s *RedisStore) ExtendLock(ctx context.Context, lock, owner string, ttl time.Duration) (bool, error) {
+	extended, err := extendLockScript.Run(ctx, s.client, []string{lock}, owner, ttl.Milliseconds()).Int64()
+	return extended == 1, err
+}
+
+func (s *RedisStore) ReleaseLock(ctx context.Context, lock, owner string) (bool, error) {
+	released, err := releaseLockScript.Run(ctx, s.client, []string{lock}, owner).Int64()
+	return released == 1, err
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
@@ -0,0 +1,205 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/burapha44/example/config"
+)
+
+// Cache is the typed cache-aside layer over a Store: keys are prefixed with
+// SERVICE_NAME, values are encoded with the CACHE_CODEC codec and concurrent
+// loads of a key share one loader call. Use it through Get, Set, Delete and
+// GetOrLoad. A nil *Cache is a cache that is switched off (CACHE_DRIVER=none):
+// every read misses, writes are dropped and GetOrLoad always calls the loader.
+type Cache struct {
+	store      Store
+	namespace  string
+	codec      Codec
+	defaultTTL time.Duration
+	loads      *singleflight.Group
+}
+
+// NewCache wraps store, the Store of NewStore. It returns a nil Cache when
+// the store is nil because the cache capability is disabled.
+func NewCache(store Store, conf *config.Config) (*Cache, error) {
+	if store == nil {
+		return nil, nil
+	}
+	codec, err := CodecFor(conf.CacheCodec)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{
+		store:      store,
+		namespace:  conf.ServiceName + ":",
+		codec:      codec,
+		defaultTTL: conf.CacheDefaultTTL,
+		loads:      &singleflight.Group{},
+	}, nil
+}
+
+// WithCodec returns a Cache that shares the store and the namespace of c but
+// encodes values with codec, for keys read by other services
+func (c *Cache) WithCodec(codec Codec) *Cache {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.codec = codec
+	return &clone
+}
+
+// Key returns the store key of key, prefixed with "<SERVICE_NAME>:"
+func (c *Cache) Key(key string) string {
+	return c.namespace + key
+}
+
+// Tag returns the store name of tag, prefixed with "<SERVICE_NAME>:tags:", so
+// keys starting with "tags:" are reserved
+func (c *Cache) Tag(tag string) string {
+	return c.namespace + "tags:" + tag
+}
+
+func (c *Cache) tags(tags []string) []string {
+	storeTags := make([]string, len(tags))
+	for i, tag := range tags {
+		storeTags[i] = c.Tag(tag)
+	}
+	return storeTags
+}
+
+// Get reads key and decodes it into a T. The bool is false when the key is
+// not cached; an entry that does not decode into T is an error.
+func Get[T any](ctx context.Context, c *Cache, key string) (T, bool, error) {
+	var value T
+	if c == nil {
+		return value, false, nil
+	}
+	data, ok, err := c.store.Get(ctx, c.Key(key))
+	if err != nil {
+		return value, false, fmt.Errorf("cache get %s: %w", key, err)
+	}
+	if !ok {
+		return value, false, nil
+	}
+	if err := c.codec.Unmarshal(data, &value); err != nil {
+		return value, false, fmt.Errorf("cache decode %s as %T: %w", key, value, err)
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for ttl, CACHE_DEFAULT_TTL when ttl is 0, and
+// adds the key to tags for InvalidateTags
+func Set[T any](ctx context.Context, c *Cache, key string, value T, ttl time.Duration, tags ...string) error {
+	if c == nil {
+		return nil
+	}
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cache encode %s: %w", key, err)
+	}
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+	if err := c.store.Set(ctx, c.Key(key), data, ttl, c.tags(tags)...); err != nil {
+		return fmt.Errorf("cache set %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes keys, missing keys are ignored
+func Delete(ctx context.Context, c *Cache, keys ...string) error {
+	if c == nil || len(keys) == 0 {
+		return nil
+	}
+	storeKeys := make([]string, len(keys))
+	for i, key := range keys {
+		storeKeys[i] = c.Key(key)
+	}
+	if err := c.store.Delete(ctx, storeKeys...); err != nil {
+		return fmt.Errorf("cache delete: %w", err)
+	}
+	return nil
+}
+
+// InvalidateTags deletes every key set with one of tags, e.g. the detail page,
+// the list pages and the aggregates of a product tagged "product:42". Within a
+// request transaction use handler.InvalidateCacheTags, which waits for the
+// commit.
+func InvalidateTags(ctx context.Context, c *Cache, tags ...string) error {
+	if c == nil || len(tags) == 0 {
+		return nil
+	}
+	if err := c.store.InvalidateTags(ctx, c.tags(tags)...); err != nil {
+		return fmt.Errorf("cache invalidate tags %v: %w", tags, err)
+	}
+	return nil
+}
+
+// GetOrLoad returns the cached value of key, or calls loader and caches its
+// result for ttl (CACHE_DEFAULT_TTL when 0) under tags. Callers asking for the
+// same key at the same time wait for a single loader call. Store errors and
+// entries that no longer decode are logged and treated as misses, so an
+// unavailable cache slows the service down instead of failing it; loader
+// errors are returned and not cached.
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), tags ...string) (T, error) {
+	if c == nil {
+		return loader(ctx)
+	}
+	value, ok, err := Get[T](ctx, c, key)
+	if ok {
+		return value, nil
+	}
+	if err != nil {
+		log.Printf("⚠️ %v, loading it", err)
+	}
+
+	// The loader runs for every waiting caller, so one of them giving up must
+	// not cancel it. The type is part of the flight key because callers of the
+	// same key with different types cannot share a result.
+	flight := c.loads.DoChan(c.Key(key)+"\x00"+reflect.TypeFor[T]().String(), func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		value, err := loader(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		if err := Set(loadCtx, c, key, value, ttl, tags...); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+		return value, nil
+	})
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case result := <-flight:
+		if result.Err != nil {
+			var zero T
+			return zero, result.Err
+		}
+		// a nil result of an interface T comes back as a nil any
+		value, _ := result.Val.(T)
+		return value, nil
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package capability
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/burapha44/example/config"
+)
+
+// Name identifies an optional subsystem of the service
+type Name string
+
+const (
+	Database Name = "database"
+	Cache    Name = "cache"
+	Session  Name = "session"
+	Docs     Name = "docs"
+)
+
+// order is the display order of the banner and the health endpoint
+var order = []Name{Database, Cache, Session, Docs}
+
+// Status is whether a subsystem is enabled and why
+type Status struct {
+	Name    Name
+	Enabled bool
+	Reason  string
+}
+
+// DisabledError is returned when code needs a subsystem that is switched off
+type DisabledError struct {
+	Name   Name
+	Reason string
+}
+
+func (e *DisabledError) Error() string {
+	return fmt.Sprintf("%s is disabled: %s", e.Name, e.Reason)
+}
+
+// Registry holds the status of the subsystems of one service, built from its
+// configuration and provided to the components that check it
+type Registry struct {
+	mu       sync.RWMutex
+	statuses map[Name]Status
+}
+
+// New registers the subsystems from the feature switches of conf. It fails
+// when an enabled subsystem misses the settings it needs.
+func New(conf *config.Config) (*Registry, error) {
+	var missing []string
+	require := func(flag, key, value string) {
+		if value == "" {
+			missing = append(missing, fmt.Sprintf("%s (needed by %s=true)", key, flag))
+		}
+	}
+
+	database := Status{Name: Database, Enabled: conf.DatabaseEnabled, Reason: "DATABASE_ENABLED=false"}
+	if database.Enabled {
+		database.Reason = "DATABASE_ENABLED=true"
+		require("DATABASE_ENABLED", "POSTGRES_HOST", conf.PostgresHost)
+		require("DATABASE_ENABLED", "POSTGRES_USER", conf.PostgresUser)
+		require("DATABASE_ENABLED", "POSTGRES_DB", conf.PostgresDB)
+	}
+
+	cache := Status{Name: Cache, Enabled: conf.CacheDriver != "none", Reason: "CACHE_DRIVER=" + conf.CacheDriver}
+	if conf.RedisEnabled {
+		require("REDIS_ENABLED", "REDIS_HOST", conf.RedisHost)
+	}
+
+	session := Status{Name: Session, Enabled: conf.SessionEnabled && cache.Enabled, Reason: "SESSION_ENABLED=true"}
+	if !conf.SessionEnabled {
+		session.Reason = "SESSION_ENABLED=false"
+	} else if !cache.Enabled {
+		session.Reason = "sessions are stored in the cache, CACHE_DRIVER=none"
+	}
+
+	docs := Status{Name: Docs, Enabled: conf.DocsEnabled, Reason: "DOCS_ENABLED=true"}
+	if !docs.Enabled {
+		docs.Reason = "DOCS_ENABLED=false"
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New("missing configurations: " + strings.Join(missing, ", "))
+	}
+
+	r := &Registry{statuses: map[Name]Status{}}
+	for _, status := range []Status{database, cache, session, docs} {
+		r.statuses[status.Name] = status
+	}
+	return r, nil
+}
+
+// Set enables or disables a subsystem at runtime
+func (r *Registry) Set(name Name, enabled bool, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[name] = Status{Name: name, Enabled: enabled, Reason: reason}
+}
+
+// Enabled reports whether the subsystem is switched on. Subsystems that were
+// never registered are disabled.
+func (r *Registry) Enabled(name Name) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.statuses[name].Enabled
+}
+
+// Require returns a *DisabledError when the subsystem is switched off
+func (r *Registry) Require(name Name) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	status, ok := r.statuses[name]
+	if !ok {
+		return &DisabledError{Name: name, Reason: "not configured"}
+	}
+	if !status.Enabled {
+		return &DisabledError{Name: name, Reason: status.Reason}
+	}
+	return nil
+}
+
+// All returns the status of every known subsystem
+func (r *Registry) All() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	statuses := make([]Status, 0, len(order))
+	for _, name := range order {
+		status, ok := r.statuses[name]
+		if !ok {
+			status = Status{Name: name, Reason: "not configured"}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
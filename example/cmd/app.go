@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -30,8 +31,8 @@ import (
 	"github.com/burapha44/example/api/v1/routes"
 	"github.com/burapha44/example/config"
 	"github.com/burapha44/example/constants"
+	"github.com/burapha44/example/di"
 	"github.com/burapha44/example/handler"
-	"github.com/burapha44/example/session"
 	"github.com/burapha44/example/utils/localized"
 )
 
@@ -93,7 +94,7 @@ func InitDocsApp() *fiber.App {
 	return app
 }
 
-func InitApp() *fiber.App {
+func InitApp(container *di.AppContainer) *fiber.App {
 	app := fiber.New(fiber.Config{
 		JSONEncoder:           json.Marshal,
 		JSONDecoder:           json.Unmarshal,
@@ -105,12 +106,7 @@ func InitApp() *fiber.App {
 		WriteTimeout:          10 * time.Second,
 	})
 
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     config.Conf.AllowOrigins,
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Request-Id, X-CSRF-Token, Referer",
-		AllowMethods:     "GET, POST, PUT, DELETE, PATCH",
-		AllowCredentials: false,
-	}))
+	app.Use(corsMiddleware(container.Config))
 
 	app.Use(func(c *fiber.Ctx) error {
 		c.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
@@ -120,7 +116,13 @@ func InitApp() *fiber.App {
 		return c.Next()
 	})
 
-	app.Use(logger.New())
+	// Access logs are written at LOG_LEVEL info and below
+	app.Use(logger.New(logger.Config{
+		Next: func(c *fiber.Ctx) bool {
+			level := container.Config.Current().LogLevel
+			return level == "warn" || level == "error"
+		},
+	}))
 
 	app.Use(compress.New(compress.Config{
 		Level: compress.LevelBestSpeed,
@@ -128,7 +130,7 @@ func InitApp() *fiber.App {
 
 	app.Use(func(c *fiber.Ctx) error {
 		lang := strings.ToLower(c.Get(fiber.HeaderAcceptLanguage, string(constants.LanguageDefault)))
-		if _, exists := localized.Language[lang]; !exists {
+		if !localized.Has(lang) {
 			lang = string(constants.LanguageDefault)
 		}
 
@@ -136,10 +138,11 @@ func InitApp() *fiber.App {
 		return c.Next()
 	})
 
-	if config.Conf.RedisHost != "" {
-		sessManager := session.NewSessionManager()
+	// Commit or roll back the request transaction if a handler began one
+	app.Use(handler.Transaction(container.DB))
 
-		app.Use(sessManager.Middleware())
+	if container.Sessions != nil {
+		app.Use(container.Sessions.Middleware())
 	}
 
 	// app.Use(csrf.New(csrf.Config{
@@ -152,20 +155,18 @@ func InitApp() *fiber.App {
 	// 	CookieSecure:   true,
 	// 	CookieHTTPOnly: true,
 	// 	// Storage: redis.New(redis.Config{
-	// 	// 	Host:      config.Conf.RedisHost,
-	// 	// 	Port:      config.Conf.RedisPort,
-	// 	// 	Password:  config.Conf.RedisPassword,
+	// 	// 	Host:      container.Config.Current().RedisHost,
+	// 	// 	Port:      container.Config.Current().RedisPort,
+	// 	// 	Password:  container.Config.Current().RedisPassword,
 	// 	// 	Database:  1,
 	// 	// 	Reset:     false,
 	// 	// 	TLSConfig: nil,
 	// 	// }),
-	// 	Session: sessManager.Store,
+	// 	Session: container.Sessions.Store,
 	// 	Next: func(c *fiber.Ctx) bool {
 	// 		return strings.HasPrefix(c.Path(), "/api/v1/external")
 	// 	},
 	// }))
-	container := routes.InitDIContainer()
-
 	// mws := container.AuthMiddleware
 	// app.Use(mws.UserAgentFilter())
 
@@ -173,3 +174,30 @@ func InitApp() *fiber.App {
 
 	return app
 }
+
+// corsMiddleware applies ALLOW_ORIGINS and rebuilds the CORS handler when a
+// configuration reload changes it
+func corsMiddleware(configs *config.Manager) fiber.Handler {
+	build := func(allowOrigins string) fiber.Handler {
+		return cors.New(cors.Config{
+			AllowOrigins:     allowOrigins,
+			AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Request-Id, X-CSRF-Token, Referer",
+			AllowMethods:     "GET, POST, PUT, DELETE, PATCH",
+			AllowCredentials: false,
+		})
+	}
+
+	var current atomic.Pointer[fiber.Handler]
+	handler := build(configs.Current().AllowOrigins)
+	current.Store(&handler)
+	configs.Subscribe(func(old, new *config.Config) {
+		if old.AllowOrigins != new.AllowOrigins {
+			handler := build(new.AllowOrigins)
+			current.Store(&handler)
+		}
+	})
+
+	return func(c *fiber.Ctx) error {
+		return (*current.Load())(c)
+	}
+}
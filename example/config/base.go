@@ -16,283 +16,196 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/burapha44/example/constants"
 )
 
+// Config is loaded from the environment by Load, see loader.go for the
+// meaning of the env, default and required tags
 type Config struct {
-	Port        string
-	Environment string
-	ServiceName string
-	Version     string
+	Port        string `env:"-"` // ":<HTTPPort>", the listen address
+	HTTPPort    int    `env:"PORT" required:"true"`
+	Environment string `env:"ENV" required:"true"`
+	ServiceName string `env:"SERVICE_NAME" default:"go-service"`
+	Version     string `env:"VERSION" default:"1.0.0"`
 
 	// Database
-	DatabaseEnabled bool
-
-	PostgresHost     string
-	PostgresPort     string
-	PostgresUser     string
-	PostgresDB       string
-	PostgresPassword string
-
-	PostgresSSLMode      string
-	PostgresRootCertLoc  string
-	PostgresMaxOpenConns int
-	PostgresMaxIdleConns int
-	PostgresMaxIdleTime  time.Duration
+	DatabaseEnabled bool `env:"DATABASE_ENABLED" default:"false"`
+
+	PostgresHost     string `env:"POSTGRES_HOST"`
+	PostgresPort     string `env:"POSTGRES_PORT" default:"5432"`
+	PostgresUser     string `env:"POSTGRES_USER"`
+	PostgresDB       string `env:"POSTGRES_DB"`
+	PostgresPassword string `env:"POSTGRES_PASSWORD" secret:"true"`
+
+	PostgresSSLMode     string `env:"POSTGRES_SSL_MODE" default:"disable"`
+	PostgresRootCertLoc string `env:"POSTGRES_ROOT_CERT_LOC"`
+
+	// Connection pool, see db.PoolConfig
+	PostgresMaxOpenConns      int           `env:"POSTGRES_MAX_OPEN_CONNS" default:"25"`
+	PostgresMinConns          int           `env:"POSTGRES_MIN_CONNS" default:"0"`
+	PostgresMinIdleConns      int           `env:"POSTGRES_MIN_IDLE_CONNS" default:"0"`
+	PostgresMaxConnLifetime   time.Duration `env:"POSTGRES_MAX_CONN_LIFETIME" default:"1h"`
+	PostgresMaxIdleTime       time.Duration `env:"POSTGRES_MAX_IDLE_TIME" default:"5m"`
+	PostgresHealthCheckPeriod time.Duration `env:"POSTGRES_HEALTH_CHECK_PERIOD" default:"1m"`
+	PostgresConnectTimeout    time.Duration `env:"POSTGRES_CONNECT_TIMEOUT" default:"5s"`
+	PostgresStatementTimeout  time.Duration `env:"POSTGRES_STATEMENT_TIMEOUT" default:"0s"` // 0 disables the timeout
+	PostgresApplicationName   string        `env:"POSTGRES_APPLICATION_NAME"`               // SERVICE_NAME when empty
+
+	// Read replicas share the credentials and pool settings of the primary, see db.ReadPool
+	PostgresReplicaHosts       []string      `env:"POSTGRES_REPLICA_HOSTS"`                 // host or host:port, comma separated
+	PostgresReplicaMaxLag      time.Duration `env:"POSTGRES_REPLICA_MAX_LAG" default:"10s"` // 0 disables the lag check
+	PostgresReplicaCheckPeriod time.Duration `env:"POSTGRES_REPLICA_CHECK_PERIOD" default:"5s"`
 
 	// Redis
-	RedisEnabled  bool
-	RedisHost     string
-	RedisPort     int
-	RedisPassword string
-
-	AllowOrigins string
-}
-
-type confVars struct {
-	missing   []string //name of the mandatory environment variable that are missing
-	malformed []string //errors describing malformed environment varibale values
+	RedisEnabled  bool   `env:"REDIS_ENABLED" default:"false"`
+	RedisHost     string `env:"REDIS_HOST"`
+	RedisPort     int    `env:"REDIS_PORT" default:"6379"`
+	RedisPassword string `env:"REDIS_PASSWORD" secret:"true"`
+
+	// Cache store of cache.Cache and the sessions, see cache.NewStore
+	CacheDriver           string        `env:"CACHE_DRIVER"` // redis, memory or none; redis when REDIS_ENABLED=true, memory otherwise
+	CacheMemoryMaxEntries int           `env:"CACHE_MEMORY_MAX_ENTRIES" default:"10000"`
+	CacheCodec            string        `env:"CACHE_CODEC" default:"json"`     // json or msgpack
+	CacheDefaultTTL       time.Duration `env:"CACHE_DEFAULT_TTL" default:"5m"` // when a TTL of 0 is given
+
+	// Session and docs, sessions are kept in the cache store
+	SessionEnabled bool `env:"SESSION_ENABLED" default:"true"`
+	DocsEnabled    bool `env:"DOCS_ENABLED" default:"true"`
+
+	// Reloaded without a restart, see Manager.Watch
+	AllowOrigins    string `env:"ALLOW_ORIGINS" default:"*" reload:"true"`
+	LogLevel        string `env:"LOG_LEVEL" default:"info" reload:"true"`
+	DefaultLanguage string `env:"DEFAULT_LANGUAGE" default:"en" reload:"true"`
+	RateLimitTiers  []int  `env:"RATE_LIMIT_TIERS" reload:"true"` // request counts of Tier0 to Tier7, e.g. 0,1,12,32,64,128,256,512
 }
 
-var Conf *Config
-
-// New initializes and returns a new Config instance by loading configuration
-// values from environment variables. It requires certain mandatory variables
-// to be set, such as PORT, ENVIRONMENT, POSTGRES_HOST, POSTGRES_PORT, POSTGRES_USER,
-// POSTGRES_DB, and POSTGRES_PASSWORD. Optional variables have default values if
-// not set, including SERVICE_NAME, VERSION, POSTGRES_SSL_MODE, POSTGRES_ROOT_CERT_LOC,
-// POSTGRES_MAX_OPEN_CONNS, POSTGRES_MAX_IDLE_CONNS, and POSTGRES_MAX_IDLE_TIME.
-// If any mandatory environment variables are missing or malformed, an error
-// is returned. The resulting Config object is assigned to the package-level
-// variable Conf.
-func New() (*Config, error) {
-	vars := &confVars{}
-
-	port := vars.mandatoryInt("PORT")
-	environment := vars.mandatory("ENV")
-	serviceName := vars.optional("SERVICE_NAME", "go-service")
-	version := vars.optional("VERSION", "1.0.0")
-
-	databaseEnabled := vars.optionalBool("DATABASE_ENABLED", false)
-	postgresHost := vars.optional("POSTGRES_HOST", "")
-	postgresPort := vars.optional("POSTGRES_PORT", "5432")
-	postgresUser := vars.optional("POSTGRES_USER", "")
-	postgresDB := vars.optional("POSTGRES_DB", "")
-	postgresPassword := vars.optional("POSTGRES_PASSWORD", "")
-
-	redisEnabled := vars.optionalBool("REDIS_ENABLED", false)
-	redisHost := vars.optional("REDIS_HOST", "")
-	redisPort := vars.optionalInt("REDIS_PORT", 6379)
-	redisPassword := vars.optional("REDIS_PASSWORD", "")
-
-	postgresSSLMode := vars.optional("POSTGRES_SSL_MODE", "disable")
-	postgresRootCertLoc := vars.optional("POSTGRES_ROOT_CERT_LOC", "")
-
-	postgresMaxOpenConns := vars.optionalInt("POSTGRES_MAX_OPEN_CONNS", constants.POSTGRES_MAX_OPEN_CONNS)
-	postgresMaxIdleConns := vars.optionalInt("POSTGRES_MAX_IDLE_CONNS", constants.POSTGRES_MAX_IDLE_CONNS)
-	postgresMaxIdleTime := vars.optionalDuration("POSTGRES_MAX_IDLE_TIME", 5*time.Minute)
-
-	allowOrigins := vars.optional("ALLOW_ORIGINS", "*")
-
-	if err := vars.Error(); err != nil {
-		return nil, fmt.Errorf("error loading configuration: %w", err)
-	}
-
-	config := &Config{
-		Port:        fmt.Sprintf(":%d", port),
-		Environment: environment,
-		ServiceName: serviceName,
-		Version:     version,
-
-		DatabaseEnabled:  databaseEnabled,
-		PostgresHost:     postgresHost,
-		PostgresPort:     postgresPort,
-		PostgresUser:     postgresUser,
-		PostgresDB:       postgresDB,
-		PostgresPassword: postgresPassword,
-
-		RedisEnabled:  redisEnabled,
-		RedisHost:     redisHost,
-		RedisPort:     redisPort,
-		RedisPassword: redisPassword,
+var logLevels = []string{"trace", "debug", "info", "warn", "error"}
 
-		PostgresSSLMode:      postgresSSLMode,
-		PostgresRootCertLoc:  postgresRootCertLoc,
-		PostgresMaxOpenConns: postgresMaxOpenConns,
-		PostgresMaxIdleConns: postgresMaxIdleConns,
-		PostgresMaxIdleTime:  postgresMaxIdleTime,
-
-		AllowOrigins: allowOrigins,
-	}
-
-	Conf = config
-
-	return config, nil
-}
+var (
+	cacheDrivers = []string{"redis", "memory", "none"}
+	cacheCodecs  = []string{"json", "msgpack"}
+)
 
-// optional returns a string value of the given environment variable. If the
-// variable is missing, it returns the fallback value. Otherwise, it returns the
-// value of the environment variable.
-func (vars *confVars) optional(key, fallback string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return fallback
-	}
-	return value
+// retiredKeys are keys older projects set that no longer have an effect, with
+// what to do instead
+var retiredKeys = map[string]string{
+	"DATABASE_ENABLE":         "rename it to DATABASE_ENABLED",
+	"REDIS_ENABLE":            "rename it to REDIS_ENABLED",
+	"POSTGRES_MAX_IDLE_CONNS": "use POSTGRES_MIN_IDLE_CONNS to keep idle connections open",
 }
 
-// optionalInt returns an integer value of the given environment variable. If the
-// variable is missing, it returns the fallback value. If the variable is not a
-// valid integer value, it appends the key to the slice of malformed variables
-// and returns the fallback value. Otherwise, it returns the parsed integer value.
-func (vars *confVars) optionalInt(key string, fallback int) int {
-	value := os.Getenv(key)
-	if value == "" {
-		return fallback
-	}
-
-	valueInt, err := strconv.Atoi(value)
-
+// New loads a Config from the sources described on Sources, taking flags from
+// args. Every missing mandatory or malformed value is reported in the returned
+// error. Services that reload their configuration use NewManager instead.
+func New(args ...string) (*Config, error) {
+	config, _, err := load(args)
 	if err != nil {
-		vars.malformed = append(vars.malformed, key)
-		return fallback
-	}
-
-	return valueInt
-}
-
-// optionalBool returns a boolean value of the given environment variable.
-// If the variable is missing, it returns the fallback value. If the variable is not a valid boolean value,
-// it appends the key to the slice of malformed variables and returns the fallback value.
-// Otherwise, it returns the parsed boolean value.
-func (vars *confVars) optionalBool(key string, fallback bool) bool {
-	value := os.Getenv(key)
-	if value == "" {
-		return fallback
-	}
-
-	valueBool, err := strconv.ParseBool(value)
-
-	if err != nil {
-		vars.malformed = append(vars.malformed, key)
-		return fallback
+		return nil, fmt.Errorf("error loading configuration: %w", err)
 	}
-
-	return valueBool
+	return config, nil
 }
 
-// optionalDuration returns a time.Duration value of the given environment variable. If the variable is
-// missing, it returns the fallback value. If the variable is not a valid time.Duration value, it appends the
-// key to the slice of malformed variables and returns the fallback value. Otherwise, it returns the parsed
-// time.Duration value.
-func (vars *confVars) optionalDuration(key string, fallback time.Duration) time.Duration {
-	value := os.Getenv(key)
-
-	if value == "" {
-		return fallback
-	}
-
-	valueDuration, err := time.ParseDuration(value)
-
+// load resolves the sources of args and loads and validates a Config from them
+func load(args []string) (*Config, *Sources, error) {
+	sources, err := ResolveSources(args)
 	if err != nil {
-		vars.malformed = append(vars.malformed, key)
-		return fallback
+		return nil, nil, err
 	}
-
-	return valueDuration
-}
-
-// mandatory returns a string value of the given environment variable. If the variable is missing, it
-// appends the key to the slice of missing variables and returns an empty string. Otherwise, it returns the
-// value of the environment variable.
-func (vars *confVars) mandatory(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		vars.missing = append(vars.missing, key)
-	}
-	return value
-}
-
-// mandatoryInt returns an integer value of the given environment variable.
-// If the variable is missing, it appends the key to the slice of missing variables and returns 0.
-// If the variable is not a valid integer value, it appends the key to the slice of malformed variables and returns 0.
-// Otherwise, it returns the parsed integer value.
-func (vars *confVars) mandatoryInt(key string) int {
-	value := os.Getenv(key)
-	if value == "" {
-		vars.missing = append(vars.missing, key)
-		return 0
-	}
-
-	valueInt, err := strconv.Atoi(value)
-
-	if err != nil {
-		vars.malformed = append(vars.malformed, key)
-		return 0
+	config := &Config{}
+	if err := LoadFrom(config, sources.Lookup); err != nil {
+		return nil, nil, err
 	}
-
-	return valueInt
-}
-
-// mandatoryDuration returns a time.Duration value of the given environment variable. If the variable is
-// missing, it appends the key to the slice of missing variables and returns 0. If the variable is not a valid
-// time.Duration value, it appends the key to the slice of malformed variables and returns 0. Otherwise, it
-// returns the parsed time.Duration value.
-func (vars *confVars) mandatoryDuration(key string) time.Duration {
-	value := os.Getenv(key)
-	if value == "" {
-		vars.missing = append(vars.missing, key)
-		return 0
+	config.Port = fmt.Sprintf(":%d", config.HTTPPort)
+	if config.CacheDriver == "" {
+		config.CacheDriver = "memory"
+		if config.RedisEnabled {
+			config.CacheDriver = "redis"
+		}
 	}
-
-	valueDuration, err := time.ParseDuration(value)
-
-	if err != nil {
-		vars.malformed = append(vars.malformed, key)
-		return 0
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
 	}
-
-	return valueDuration
+	return config, sources, nil
 }
 
-// mandatoryBool returns a boolean value of the given environment variable. If the variable is missing, it
-// appends the key to the slice of missing variables and returns false. If the variable is not a valid boolean
-// value, it appends the key to the slice of malformed variables and returns false. Otherwise, it returns the
-// parsed boolean value.
-func (vars *confVars) mandatoryBool(key string) bool {
-	value := os.Getenv(key)
-	if value == "" {
-		vars.missing = append(vars.missing, key)
-		return false
+// Validate checks the values the loader cannot: ranges and fixed choices
+func (c *Config) Validate() error {
+	var problems []string
+	if !slices.Contains(logLevels, c.LogLevel) {
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL=%q (expected one of %s)", c.LogLevel, strings.Join(logLevels, ", ")))
+	}
+	if !slices.Contains(cacheDrivers, c.CacheDriver) {
+		problems = append(problems, fmt.Sprintf("CACHE_DRIVER=%q (expected one of %s)", c.CacheDriver, strings.Join(cacheDrivers, ", ")))
+	} else if c.CacheDriver == "redis" && !c.RedisEnabled {
+		problems = append(problems, "CACHE_DRIVER=redis (needs REDIS_ENABLED=true)")
+	}
+	if c.CacheMemoryMaxEntries < 1 {
+		problems = append(problems, fmt.Sprintf("CACHE_MEMORY_MAX_ENTRIES=%d (expected at least 1)", c.CacheMemoryMaxEntries))
+	}
+	if !slices.Contains(cacheCodecs, c.CacheCodec) {
+		problems = append(problems, fmt.Sprintf("CACHE_CODEC=%q (expected one of %s)", c.CacheCodec, strings.Join(cacheCodecs, ", ")))
+	}
+	if len(c.RateLimitTiers) > 0 && len(c.RateLimitTiers) != len(constants.Tiers) {
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_TIERS has %d values (expected %d, Tier0 to Tier%d)", len(c.RateLimitTiers), len(constants.Tiers), len(constants.Tiers)-1))
+	}
+	if c.PostgresMaxOpenConns < 1 {
+		problems = append(problems, fmt.Sprintf("POSTGRES_MAX_OPEN_CONNS=%d (expected at least 1)", c.PostgresMaxOpenConns))
+	}
+	if c.PostgresMinConns < 0 || c.PostgresMinConns > c.PostgresMaxOpenConns {
+		problems = append(problems, fmt.Sprintf("POSTGRES_MIN_CONNS=%d (expected 0 to POSTGRES_MAX_OPEN_CONNS)", c.PostgresMinConns))
+	}
+	if c.PostgresMinIdleConns < 0 || c.PostgresMinIdleConns > c.PostgresMaxOpenConns {
+		problems = append(problems, fmt.Sprintf("POSTGRES_MIN_IDLE_CONNS=%d (expected 0 to POSTGRES_MAX_OPEN_CONNS)", c.PostgresMinIdleConns))
+	}
+	// pgx takes a zero lifetime, idle time or health check period literally and
+	// Redis keeps keys set with a zero TTL forever, while zero timeouts and lag
+	// mean none
+	for _, d := range []struct {
+		key      string
+		value    time.Duration
+		positive bool
+	}{
+		{"POSTGRES_MAX_CONN_LIFETIME", c.PostgresMaxConnLifetime, true},
+		{"POSTGRES_MAX_IDLE_TIME", c.PostgresMaxIdleTime, true},
+		{"POSTGRES_HEALTH_CHECK_PERIOD", c.PostgresHealthCheckPeriod, true},
+		{"POSTGRES_REPLICA_CHECK_PERIOD", c.PostgresReplicaCheckPeriod, true},
+		{"CACHE_DEFAULT_TTL", c.CacheDefaultTTL, true},
+		{"POSTGRES_CONNECT_TIMEOUT", c.PostgresConnectTimeout, false},
+		{"POSTGRES_STATEMENT_TIMEOUT", c.PostgresStatementTimeout, false},
+		{"POSTGRES_REPLICA_MAX_LAG", c.PostgresReplicaMaxLag, false},
+	} {
+		if d.positive && d.value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s=%s (expected a positive duration)", d.key, d.value))
+		} else if d.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s=%s (expected 0 or a positive duration)", d.key, d.value))
+		}
+	}
+	for _, count := range c.RateLimitTiers {
+		if count < 0 {
+			problems = append(problems, fmt.Sprintf("RATE_LIMIT_TIERS contains %d (expected counts >= 0)", count))
+			break
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configurations: " + strings.Join(problems, "; "))
 	}
-
-	valueBool, err := strconv.ParseBool(value)
-
-	if err != nil {
-		vars.malformed = append(vars.malformed, key)
-		return false
-	}
-
-	return valueBool
+	return nil
 }
 
-// Error returns an error if any of the mandatory environment variables are
-// not set or if any of the variables have malformed values. The error message
-// will list out the names of the variables that are missing or malformed. If
-// all variables are set and have valid values, the method returns nil.
-func (vars confVars) Error() error {
-	if len(vars.missing) > 0 {
-		return fmt.Errorf("missing mandatory configurations: %s", strings.Join(vars.missing, ", "))
+// RateLimit returns the request count of the tier whose default count is
+// count, as configured by RATE_LIMIT_TIERS
+func (c *Config) RateLimit(count int) int {
+	if len(c.RateLimitTiers) == 0 {
+		return count
 	}
-
-	if len(vars.malformed) > 0 {
-		return fmt.Errorf("malformed configurations: %s", strings.Join(vars.malformed, "; "))
+	if i := slices.Index(constants.Tiers, count); i >= 0 {
+		return c.RateLimitTiers[i]
 	}
-	return nil
+	return count
 }
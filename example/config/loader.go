@@ -0,0 +1,295 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields are loaded according to their struct tags:
+//
+//	env:"REDIS_PORT"   name of the variable; "-" skips the field. On a nested
+//	                   struct the tag is a prefix for the keys of its fields
+//	                   (embedded structs without a tag add no prefix).
+//	default:"6379"     value used when the variable is unset or empty
+//	required:"true"    report the variable as missing when it has no value
+//	secret:"true"      hide the value when the configuration is printed
+//	reload:"true"      the value is applied by Reload without a restart
+//
+// Supported types are strings, bools, ints, uints, floats, time.Duration,
+// url.URL, types implementing encoding.TextUnmarshaler, slices of those
+// (comma separated) and nested structs.
+
+// LookupFunc returns the value of a configuration key and whether it is set
+type LookupFunc func(key string) (string, bool)
+
+// FieldError describes a configuration value that cannot be parsed
+type FieldError struct {
+	Key      string
+	Value    string
+	Expected string
+}
+
+// LoadError lists every missing and malformed key found by Load
+type LoadError struct {
+	Missing   []string
+	Malformed []FieldError
+}
+
+func (e *LoadError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing mandatory configurations: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Malformed) > 0 {
+		malformed := make([]string, 0, len(e.Malformed))
+		for _, f := range e.Malformed {
+			malformed = append(malformed, fmt.Sprintf("%s=%q (expected %s)", f.Key, f.Value, f.Expected))
+		}
+		parts = append(parts, "malformed configurations: "+strings.Join(malformed, "; "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Load fills the struct pointed to by dst from environment variables
+func Load(dst interface{}) error {
+	return LoadFrom(dst, os.LookupEnv)
+}
+
+// LoadFrom fills the struct pointed to by dst from lookup. All missing and
+// malformed keys are reported together in a *LoadError.
+func LoadFrom(dst interface{}, lookup LookupFunc) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Load needs a pointer to a struct, got %T", dst)
+	}
+	loadErr := &LoadError{}
+	if err := loadStruct(v.Elem(), "", lookup, loadErr); err != nil {
+		return err
+	}
+	if len(loadErr.Missing) > 0 || len(loadErr.Malformed) > 0 {
+		return loadErr
+	}
+	return nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Field is a configuration key of a struct passed to Load
+type Field struct {
+	Key      string
+	Default  string
+	Required bool
+	Secret   bool // tagged secret:"true", hidden when the configuration is printed
+	Reload   bool // tagged reload:"true", applied without a restart
+}
+
+// Fields returns the configuration keys of the struct pointed to by dst in
+// declaration order
+func Fields(dst interface{}) ([]Field, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: Fields needs a pointer to a struct, got %T", dst)
+	}
+	var fields []Field
+	err := walkFields(v.Elem(), "", func(key string, field reflect.StructField, _ reflect.Value) {
+		fields = append(fields, Field{
+			Key:      key,
+			Default:  field.Tag.Get("default"),
+			Required: field.Tag.Get("required") == "true",
+			Secret:   field.Tag.Get("secret") == "true",
+			Reload:   field.Tag.Get("reload") == "true",
+		})
+	})
+	return fields, err
+}
+
+func loadStruct(v reflect.Value, prefix string, lookup LookupFunc, loadErr *LoadError) error {
+	return walkFields(v, prefix, func(key string, field reflect.StructField, fv reflect.Value) {
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			value, ok = field.Tag.Lookup("default")
+		}
+		if !ok || value == "" {
+			if field.Tag.Get("required") == "true" {
+				loadErr.Missing = append(loadErr.Missing, key)
+			}
+			return
+		}
+		if err := setValue(fv, value); err != nil {
+			loadErr.Malformed = append(loadErr.Malformed, FieldError{Key: key, Value: value, Expected: expectedType(field.Type)})
+		}
+	})
+}
+
+// walkFields calls fn with the key of every tagged field of v, descending
+// into nested structs
+func walkFields(v reflect.Value, prefix string, fn func(key string, field reflect.StructField, v reflect.Value)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup("env")
+		if tag == "-" {
+			continue
+		}
+
+		if isNestedStruct(field.Type) {
+			nestedPrefix := prefix
+			if hasTag {
+				nestedPrefix += tag
+			}
+			if err := walkFields(v.Field(i), nestedPrefix, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if !hasTag {
+			return fmt.Errorf("config: field %s.%s has no env tag", t.Name(), field.Name)
+		}
+		fn(prefix+tag, field, v.Field(i))
+	}
+	return nil
+}
+
+// isNestedStruct reports whether t is a struct holding configuration fields
+// rather than a single value
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != urlType && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setValue parses raw into v
+func setValue(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Ptr {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == urlType:
+		u, err := url.Parse(raw)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%q is not an absolute URL", raw)
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(raw, ",")
+		slice := reflect.MakeSlice(v.Type(), 0, len(parts))
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, part); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// expectedType describes t for error messages
+func expectedType(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return "duration, e.g. 30s or 5m"
+	case t == urlType:
+		return "absolute URL"
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return t.String()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool (true/false)"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "comma separated list of " + expectedType(t.Elem())
+	}
+	return t.String()
+}
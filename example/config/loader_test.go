@@ -0,0 +1,94 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type loaderTestConfig struct {
+	Name    string        `env:"NAME" required:"true"`
+	Port    int           `env:"PORT" default:"8080"`
+	Debug   bool          `env:"DEBUG"`
+	Timeout time.Duration `env:"TIMEOUT" required:"true"`
+	Tiers   []int         `env:"TIERS"`
+	Redis   struct {
+		Host string `env:"HOST" required:"true"`
+		Port uint16 `env:"PORT" default:"6379"`
+	} `env:"REDIS_"`
+}
+
+func mapLookup(values map[string]string) LookupFunc {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	var conf loaderTestConfig
+	err := LoadFrom(&conf, mapLookup(map[string]string{
+		"NAME":       "api",
+		"DEBUG":      "true",
+		"TIMEOUT":    "5s",
+		"TIERS":      "1, 2,,3",
+		"REDIS_HOST": "redis",
+		"PORT":       "", // empty values fall back to the default
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "api" || conf.Port != 8080 || !conf.Debug || conf.Timeout != 5*time.Second ||
+		!slices.Equal(conf.Tiers, []int{1, 2, 3}) || conf.Redis.Host != "redis" || conf.Redis.Port != 6379 {
+		t.Errorf("unexpected configuration %+v", conf)
+	}
+}
+
+func TestLoadFromReportsEveryError(t *testing.T) {
+	var conf loaderTestConfig
+	err := LoadFrom(&conf, mapLookup(map[string]string{
+		"PORT":       "http",
+		"DEBUG":      "maybe",
+		"TIERS":      "1,x",
+		"REDIS_PORT": "70000",
+	}))
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("err = %v, want a *LoadError", err)
+	}
+	if want := []string{"NAME", "TIMEOUT", "REDIS_HOST"}; !slices.Equal(loadErr.Missing, want) {
+		t.Errorf("Missing = %v, want %v", loadErr.Missing, want)
+	}
+	want := []FieldError{
+		{Key: "PORT", Value: "http", Expected: "integer"},
+		{Key: "DEBUG", Value: "maybe", Expected: "bool (true/false)"},
+		{Key: "TIERS", Value: "1,x", Expected: "comma separated list of integer"},
+		{Key: "REDIS_PORT", Value: "70000", Expected: "non-negative integer"},
+	}
+	if !slices.Equal(loadErr.Malformed, want) {
+		t.Errorf("Malformed = %+v, want %+v", loadErr.Malformed, want)
+	}
+}
+
+func TestLoadFromNeedsStructPointer(t *testing.T) {
+	var conf loaderTestConfig
+	if err := LoadFrom(conf, mapLookup(nil)); err == nil {
+		t.Error("want an error for a struct value")
+	}
+}
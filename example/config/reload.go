@@ -0,0 +1,264 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Subscriber is called after a reloaded configuration has been swapped in,
+// with the previous and the new snapshot. Snapshots are never modified.
+type Subscriber func(old, new *Config)
+
+// Manager holds the configuration of a running service: the latest valid
+// snapshot, the sources it was read from and the subscribers to its reloads.
+// Each Manager reloads independently, so a process can run several services.
+type Manager struct {
+	args    []string
+	current atomic.Pointer[Config]
+	sources atomic.Pointer[Sources]
+
+	reloadMu    sync.Mutex // serialises Reload
+	subscribers []Subscriber
+}
+
+// NewManager loads the configuration like New, taking flags from args, and
+// exports the values read from files to the process environment
+// (Sources.Export). Retired keys still found in the sources are logged.
+func NewManager(args ...string) (*Manager, error) {
+	config, sources, err := load(args)
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+	sources.Export()
+	for _, key := range slices.Sorted(maps.Keys(retiredKeys)) {
+		if _, ok := sources.Lookup(key); ok {
+			log.Printf("⚠️ %s is ignored, %s", key, retiredKeys[key])
+		}
+	}
+
+	m := &Manager{args: args}
+	m.current.Store(config)
+	m.sources.Store(sources)
+	return m, nil
+}
+
+// Current returns the latest valid configuration. It is safe to call from
+// any goroutine, e.g. once per request.
+func (m *Manager) Current() *Config {
+	return m.current.Load()
+}
+
+// Sources returns the sources of the current configuration
+func (m *Manager) Sources() *Sources {
+	return m.sources.Load()
+}
+
+// Subscribe registers fn to be called after every successful reload
+func (m *Manager) Subscribe(fn Subscriber) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Reload reads every source again with the arguments given to NewManager,
+// validates the result and swaps it in before notifying the subscribers, even
+// when no value changed (SIGHUP also reloads what subscribers read from
+// elsewhere, such as the language files). Only the keys tagged reload:"true"
+// take their new values; the others keep the values the running components
+// were built with until a restart. It returns the changed keys, reloadable or
+// not. The running configuration is kept when the new one is invalid.
+func (m *Manager) Reload() ([]string, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	loaded, sources, err := load(m.args)
+	if err != nil {
+		return nil, err
+	}
+	old := m.Current()
+	changed, err := changedKeys(old, loaded)
+	if err != nil {
+		return nil, err
+	}
+	config, err := reloadable(old, loaded)
+	if err != nil {
+		return nil, err
+	}
+	m.current.Store(config)
+	m.sources.Store(sources)
+	for _, fn := range m.subscribers {
+		notify(fn, old, config)
+	}
+	return changed, nil
+}
+
+// notify calls a subscriber, so that a panicking one cannot stop the others
+func notify(fn Subscriber, old, new *Config) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ Configuration subscriber panicked: %v", r)
+		}
+	}()
+	fn(old, new)
+}
+
+// reloadable returns a copy of old with the keys tagged reload:"true" taken
+// from new
+func reloadable(old, new *Config) (*Config, error) {
+	if old == nil {
+		return new, nil
+	}
+	newValues, err := keyValues(new)
+	if err != nil {
+		return nil, err
+	}
+	config := *old
+	err = walkFields(reflect.ValueOf(&config).Elem(), "", func(key string, field reflect.StructField, v reflect.Value) {
+		if field.Tag.Get("reload") == "true" {
+			v.Set(reflect.ValueOf(newValues[key]))
+		}
+	})
+	return &config, err
+}
+
+// changedKeys returns the keys whose values differ between two snapshots
+func changedKeys(old, new *Config) ([]string, error) {
+	if old == nil {
+		return nil, nil
+	}
+	oldValues, err := keyValues(old)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	err = walkFields(reflect.ValueOf(new).Elem(), "", func(key string, _ reflect.StructField, v reflect.Value) {
+		if !reflect.DeepEqual(oldValues[key], v.Interface()) {
+			changed = append(changed, key)
+		}
+	})
+	return changed, err
+}
+
+func keyValues(c *Config) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	err := walkFields(reflect.ValueOf(c).Elem(), "", func(key string, _ reflect.StructField, v reflect.Value) {
+		values[key] = v.Interface()
+	})
+	return values, err
+}
+
+// Watch reloads the configuration on SIGHUP and whenever the YAML file, an
+// env file or the secrets file of the environment is written, created or
+// replaced, until ctx is done. Failed reloads are logged and the running configuration is kept.
+func (m *Manager) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("cannot watch configuration files: %w", err)
+	}
+	defer watcher.Close()
+
+	// Directories are watched rather than files so editors that replace the
+	// file and files created after startup are noticed
+	files := map[string]bool{}
+	sources := m.Sources()
+	watched := append([]string{sources.ConfigFile}, EnvFiles(sources.Env)...)
+	for _, file := range append(watched, SecretsFile(sources.Env)) {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		files[path] = true
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("cannot watch %s: %w", filepath.Dir(path), err)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			m.reloadAndLog("SIGHUP")
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			path, _ := filepath.Abs(event.Name)
+			if files[path] && !event.Has(fsnotify.Chmod) {
+				// editors write files in several steps
+				debounce = time.After(200 * time.Millisecond)
+			}
+		case <-debounce:
+			debounce = nil
+			m.reloadAndLog("file change")
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("⚠️ Configuration watcher: %v", err)
+		}
+	}
+}
+
+func (m *Manager) reloadAndLog(reason string) {
+	changed, err := m.Reload()
+	if err != nil {
+		log.Printf("❌ Configuration not reloaded (%s), keeping the running one: %v", reason, err)
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+	fields, _ := Fields(&Config{})
+	reloadable := map[string]bool{}
+	for _, f := range fields {
+		reloadable[f.Key] = f.Reload
+	}
+	var applied, restart []string
+	for _, key := range changed {
+		if reloadable[key] {
+			applied = append(applied, key)
+		} else {
+			restart = append(restart, key)
+		}
+	}
+	if len(applied) > 0 {
+		log.Printf("🔄 Configuration reloaded (%s), applied: %v", reason, applied)
+	}
+	if len(restart) > 0 {
+		log.Printf("⚠️ Configuration changed (%s), restart to apply: %v", reason, restart)
+	}
+}
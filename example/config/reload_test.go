@@ -0,0 +1,86 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestReloadableKeepsRestartOnlyValues(t *testing.T) {
+	old := &Config{ServiceName: "api", Port: ":8080", PostgresHost: "db-1", LogLevel: "info", AllowOrigins: "*"}
+	loaded := &Config{ServiceName: "renamed", Port: ":9090", PostgresHost: "db-2", LogLevel: "debug", AllowOrigins: "https://example.com", RateLimitTiers: []int{0, 1}}
+
+	config, err := reloadable(old, loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ServiceName != "api" || config.Port != ":8080" || config.PostgresHost != "db-1" {
+		t.Errorf("restart-only values changed: %q %q %q", config.ServiceName, config.Port, config.PostgresHost)
+	}
+	if config.LogLevel != "debug" || config.AllowOrigins != "https://example.com" || !slices.Equal(config.RateLimitTiers, []int{0, 1}) {
+		t.Errorf("reloadable values not applied: %q %q %v", config.LogLevel, config.AllowOrigins, config.RateLimitTiers)
+	}
+	if old.LogLevel != "info" {
+		t.Error("the old snapshot was modified")
+	}
+
+	changed, err := changedKeys(old, loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"SERVICE_NAME", "POSTGRES_HOST", "LOG_LEVEL"} {
+		if !slices.Contains(changed, key) {
+			t.Errorf("changed keys %v miss %s", changed, key)
+		}
+	}
+}
+
+func TestResolveSourcesAndExportConcurrently(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("RELOAD_TEST_KEY: value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv("RELOAD_TEST_KEY")
+		exportedMu.Lock()
+		delete(exported, "RELOAD_TEST_KEY")
+		exportedMu.Unlock()
+	})
+
+	// Reloads resolve and export while the application may do the same. Unsetting
+	// the variable makes every Export write it again; run with -race.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				s, err := ResolveSources([]string{"--config", file})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				os.Unsetenv("RELOAD_TEST_KEY")
+				s.Export()
+			}
+		}()
+	}
+	wg.Wait()
+}
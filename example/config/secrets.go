@@ -0,0 +1,172 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Secrets are kept in .env.<env>.enc next to the plain env files. Keys stay
+// readable and every value is encrypted on its own with AES-256-GCM, bound to
+// its key name:
+//
+//	POSTGRES_PASSWORD=enc:v1:<base64 nonce and ciphertext>
+//
+// The file is written by nvs secrets set|edit|rotate and decrypted in memory
+// when the configuration is loaded. The 32 byte key is read from, in order,
+// NVS_SECRETS_KEY (base64), the file named by NVS_SECRETS_KEY_FILE or
+// .nvs/secrets.key, which must never be committed.
+
+const (
+	encryptedPrefix = "enc:v1:"
+
+	// DefaultSecretsKeyFile is the local key written by nvs secrets
+	DefaultSecretsKeyFile = ".nvs/secrets.key"
+)
+
+// SecretsFile returns the encrypted env file of env
+func SecretsFile(env string) string {
+	return ".env." + env + ".enc"
+}
+
+// IsEncrypted reports whether value was written by EncryptValue
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// LoadSecretsKey returns the key that decrypts the secrets files
+func LoadSecretsKey() ([]byte, error) {
+	if encoded := os.Getenv("NVS_SECRETS_KEY"); encoded != "" {
+		return decodeSecretsKey(encoded, "NVS_SECRETS_KEY")
+	}
+	path := DefaultSecretsKeyFile
+	if file := os.Getenv("NVS_SECRETS_KEY_FILE"); file != "" {
+		path = file
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no secrets key: set NVS_SECRETS_KEY or NVS_SECRETS_KEY_FILE, or create %s with nvs secrets", DefaultSecretsKeyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSecretsKey(string(data), filepath.Base(path))
+}
+
+func decodeSecretsKey(encoded, source string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s is not a base64 encoded 32 byte key", source)
+	}
+	return key, nil
+}
+
+// EncryptValue encrypts the value of the configuration key name
+func EncryptValue(key []byte, name, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts a value written by EncryptValue for the key name
+func DecryptValue(key []byte, name, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("%s is not encrypted", name)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("%s: malformed encrypted value", name)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("%s: malformed encrypted value", name)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("%s: cannot decrypt, wrong key or modified value", name)
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptValues decrypts the encrypted values of a secrets file in place
+func decryptValues(file string, values map[string]string) error {
+	var key []byte
+	for name, value := range values {
+		if !IsEncrypted(value) {
+			continue
+		}
+		if key == nil {
+			var err error
+			if key, err = LoadSecretsKey(); err != nil {
+				return fmt.Errorf("cannot decrypt %s: %w", file, err)
+			}
+		}
+		plain, err := DecryptValue(key, name, value)
+		if err != nil {
+			return fmt.Errorf("cannot decrypt %s: %w", file, err)
+		}
+		values[name] = plain
+	}
+	return nil
+}
+
+// resolveFileValues sets KEY from the contents of the file named by KEY_FILE,
+// the convention of Docker and Kubernetes secrets. A value set for KEY itself
+// in the same layer wins. Only the keys in only are resolved when it is not nil.
+func resolveFileValues(layer string, values map[string]string, only map[string]bool) error {
+	resolved := map[string]string{}
+	for name, path := range values {
+		key, ok := strings.CutSuffix(name, "_FILE")
+		if !ok || key == "" || path == "" || values[key] != "" || (only != nil && !only[key]) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", name, layer, err)
+		}
+		resolved[key] = strings.TrimRight(string(data), "\r\n")
+	}
+	for key, value := range resolved {
+		values[key] = value
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the optional YAML file read before the env files
+const DefaultConfigFile = "config.yaml"
+
+// Layer is one configuration source with the values it sets
+type Layer struct {
+	Name   string // config.yaml, .env.dev, environment or flags
+	Values map[string]string
+}
+
+// Sources are the layers a Config is loaded from, lowest precedence first:
+//
+//  1. the default tags of Config
+//  2. config.yaml, nested keys are joined with "_" (postgres.host is POSTGRES_HOST)
+//  3. the env files of the environment, see EnvFiles, then the encrypted
+//     SecretsFile
+//  4. environment variables
+//  5. command-line flags, --env=prod selects the environment and every key
+//     has a flag of its own (--port=8080, --redis-host=cache)
+//
+// Missing files are skipped, so a container that only sets environment
+// variables needs no file at all. In every layer KEY_FILE=/path sets KEY to
+// the contents of the file, as Docker and Kubernetes secrets are mounted.
+type Sources struct {
+	Env        string // selected environment: dev, staging, prod, ...
+	EnvOrigin  string // where Env came from: flags, environment or default
+	ConfigFile string // path of the YAML file, read when it exists
+	Layers     []Layer
+}
+
+// EnvFiles returns the env files read for env, lowest precedence first.
+// .env.local (dev) and .env (prod) are still read for projects created
+// before per-environment files.
+func EnvFiles(env string) []string {
+	switch env {
+	case "dev":
+		return []string{".env.dev", ".env.local"}
+	case "prod":
+		return []string{".env", ".env.prod"}
+	}
+	return []string{".env." + env}
+}
+
+// FlagName returns the command-line flag of a configuration key
+func FlagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+// ResolveSources reads every configuration source, taking flags from args
+func ResolveSources(args []string) (*Sources, error) {
+	fields, err := Fields(&Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	fs.String("env", "", "environment to load (dev, staging, prod), overrides ENV")
+	configFile := fs.String("config", DefaultConfigFile, "YAML configuration file")
+	for _, f := range fields {
+		if fs.Lookup(FlagName(f.Key)) == nil {
+			fs.String(FlagName(f.Key), "", "overrides "+f.Key)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	flags := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			flags[strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))] = f.Value.String()
+		}
+	})
+
+	s := &Sources{Env: "dev", EnvOrigin: "default", ConfigFile: *configFile}
+	if env, ok := flags["ENV"]; ok && env != "" {
+		s.Env, s.EnvOrigin = env, "flags"
+	} else if env := os.Getenv("ENV"); env != "" {
+		s.Env, s.EnvOrigin = env, "environment"
+	}
+
+	values, err := readYAML(*configFile)
+	if err == nil {
+		if err := resolveFileValues(*configFile, values, nil); err != nil {
+			return nil, err
+		}
+		s.Layers = append(s.Layers, Layer{Name: *configFile, Values: values})
+	} else if !errors.Is(err, os.ErrNotExist) || *configFile != DefaultConfigFile {
+		return nil, err
+	}
+
+	for _, file := range append(EnvFiles(s.Env), SecretsFile(s.Env)) {
+		values, err := godotenv.Read(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", file, err)
+		}
+		if err := decryptValues(file, values); err != nil {
+			return nil, err
+		}
+		if err := resolveFileValues(file, values, nil); err != nil {
+			return nil, err
+		}
+		s.Layers = append(s.Layers, Layer{Name: file, Values: values})
+	}
+
+	// Only declared keys are read from _FILE variables of the environment,
+	// which may hold unrelated ones
+	declared := map[string]bool{}
+	for _, f := range fields {
+		declared[f.Key] = true
+	}
+	environment := map[string]string{}
+	exportedMu.Lock()
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok && !exported[key] {
+			environment[key] = value
+		}
+	}
+	exportedMu.Unlock()
+	if err := resolveFileValues("environment", environment, declared); err != nil {
+		return nil, err
+	}
+	s.Layers = append(s.Layers, Layer{Name: "environment", Values: environment})
+	s.Layers = append(s.Layers, Layer{Name: "flags", Values: flags})
+	return s, nil
+}
+
+// Lookup returns the value of key from the layer with the highest precedence
+// that sets it to a non-empty value. ENV is always the selected environment.
+func (s *Sources) Lookup(key string) (string, bool) {
+	if key == "ENV" {
+		return s.Env, true
+	}
+	for i := len(s.Layers) - 1; i >= 0; i-- {
+		if value := s.Layers[i].Values[key]; value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Origin returns the name of the layer Lookup takes key from, or "default"
+func (s *Sources) Origin(key string) string {
+	if key == "ENV" {
+		return s.EnvOrigin
+	}
+	for i := len(s.Layers) - 1; i >= 0; i-- {
+		if s.Layers[i].Values[key] != "" {
+			return s.Layers[i].Name
+		}
+	}
+	return "default"
+}
+
+// Files returns the configuration files that were found
+func (s *Sources) Files() []string {
+	var files []string
+	for _, layer := range s.Layers {
+		if layer.Name != "environment" && layer.Name != "flags" {
+			files = append(files, layer.Name)
+		}
+	}
+	return files
+}
+
+// exported are the variables Export set, which are not environment
+// variables of the process when the sources are resolved again. Reloads
+// resolve and export from their own goroutine, hence exportedMu.
+var (
+	exportedMu sync.Mutex
+	exported   = map[string]bool{}
+)
+
+// Export sets the values of the files as environment variables that are not
+// set yet, for code that reads os.Getenv directly
+func (s *Sources) Export() {
+	exportedMu.Lock()
+	defer exportedMu.Unlock()
+	for _, layer := range s.Layers {
+		if layer.Name == "environment" || layer.Name == "flags" {
+			continue
+		}
+		for key := range layer.Values {
+			if _, ok := os.LookupEnv(key); !ok {
+				value, _ := s.Lookup(key)
+				os.Setenv(key, value)
+				exported[key] = true
+			}
+		}
+	}
+}
+
+// readYAML reads a YAML file into configuration keys. Nested mappings are
+// joined with "_" and sequences become comma separated lists.
+func readYAML(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	values := map[string]string{}
+	if err := flattenYAML("", doc, values); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flattenYAML(prefix string, node map[string]interface{}, values map[string]string) error {
+	for name, value := range node {
+		key := prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		switch value := value.(type) {
+		case nil:
+		case map[string]interface{}:
+			if err := flattenYAML(key+"_", value, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				switch item.(type) {
+				case map[string]interface{}, []interface{}:
+					return fmt.Errorf("%s: lists may only hold plain values", key)
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
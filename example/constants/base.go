@@ -25,6 +25,12 @@ const (
 	LanguageThai    Language = "th"
 )
 
+// SessionCookie is the cookie holding the session id
+const SessionCookie string = "sid"
+
+// AuthTokenKey is the ctx.Locals key holding what BaseMiddleware.VerifyToken returned for the request
+const AuthTokenKey string = "auth_token"
+
 const (
 	POSTGRES_MAX_IDLE_CONNS = 25
 	POSTGRES_MAX_OPEN_CONNS = 25
@@ -48,6 +54,10 @@ const (
 	Tier7 = 512
 )
 
+// Tiers lists the default request counts of Tier0 to Tier7. RATE_LIMIT_TIERS
+// replaces them, by position, without a restart.
+var Tiers = []int{Tier0, Tier1, Tier2, Tier3, Tier4, Tier5, Tier6, Tier7}
+
 const (
 	MaxFailedAttempts = 5
 )
@@ -67,4 +77,5 @@ const (
 	UnableToRollbackTrxCode string = "ERR_UNABLE_TO_ROLLBACK_TRX"
 	UnableToGetUserCode     string = "ERR_UNABLE_TO_GET_USER"
 	UnauthorizedCode        string = "ERR_UNAUTHORIZED"
+	FeatureDisabledCode     string = "ERR_FEATURE_DISABLED"
 )
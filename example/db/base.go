@@ -19,57 +19,148 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/burapha44/example/capability"
 	"github.com/burapha44/example/config"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var PostgresConn *pgxpool.Pool
+// DB is the PostgreSQL primary pool and its read replicas. It is created by
+// New and injected where it is needed; handler.Transaction hands it to the
+// request transaction.
+type DB struct {
+	conf     *config.Config
+	primary  *pgxpool.Pool
+	replicas []*replica
+	next     atomic.Uint64
+
+	stopReplicas context.CancelFunc
+	replicasDone sync.WaitGroup
+}
 
 // GetPostgresURL returns the connection string for the PostgreSQL database.
-func GetPostgresURL() string {
-	dbHost := config.Conf.PostgresHost
-	dbPort := config.Conf.PostgresPort
-	dbUser := config.Conf.PostgresUser
-	dbPass := config.Conf.PostgresPassword
-	dbName := config.Conf.PostgresDB
-
-	if config.Conf.PostgresSSLMode == "disable" {
-		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-			dbHost, dbPort, dbUser, dbPass, dbName)
-	} else {
-		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s sslrootcert=%s",
-			dbHost, dbPort, dbUser, dbPass, dbName, config.Conf.PostgresSSLMode, config.Conf.PostgresRootCertLoc)
+func GetPostgresURL(conf *config.Config) string {
+	return postgresURL(conf, conf.PostgresHost, conf.PostgresPort)
+}
+
+// postgresURL is GetPostgresURL for the server at host and port, so TLS
+// verification and fallbacks follow that server
+func postgresURL(conf *config.Config, host, port string) string {
+	url := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		dsnValue(host), dsnValue(port), dsnValue(conf.PostgresUser),
+		dsnValue(conf.PostgresPassword), dsnValue(conf.PostgresDB), dsnValue(conf.PostgresSSLMode))
+	if conf.PostgresSSLMode != "disable" && conf.PostgresRootCertLoc != "" {
+		url += " sslrootcert=" + dsnValue(conf.PostgresRootCertLoc)
 	}
+	return url
 }
 
-// Init initializes the database connection using pgx.
-func Init() error {
-	var err error
-	PostgresConn, err = pgxpool.New(context.Background(), GetPostgresURL())
+// dsnValue quotes a connection string value, so empty values and values with
+// spaces or quotes do not run into the next keyword
+func dsnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// PoolConfig returns the pool configuration parsed from GetPostgresURL with the
+// POSTGRES_* pool settings of conf applied, before any connection opens.
+func PoolConfig(conf *config.Config) (*pgxpool.Config, error) {
+	return poolConfigFor(conf, GetPostgresURL(conf))
+}
+
+// poolConfigFor parses url and applies the POSTGRES_* pool settings of conf
+func poolConfigFor(conf *config.Config, url string) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(url)
 	if err != nil {
-		return fmt.Errorf("error opening database connection: %w", err)
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
 	}
 
-	// Ping to check the connection
-	if err := PostgresConn.Ping(context.Background()); err != nil {
-		return fmt.Errorf("error pinging database: %w", err)
+	poolConfig.MaxConns = int32(conf.PostgresMaxOpenConns)
+	poolConfig.MinConns = int32(conf.PostgresMinConns)
+	poolConfig.MinIdleConns = int32(conf.PostgresMinIdleConns)
+	poolConfig.MaxConnLifetime = conf.PostgresMaxConnLifetime
+	poolConfig.MaxConnIdleTime = conf.PostgresMaxIdleTime
+	poolConfig.HealthCheckPeriod = conf.PostgresHealthCheckPeriod
+	poolConfig.ConnConfig.ConnectTimeout = conf.PostgresConnectTimeout
+
+	// Sent in the startup message, so they apply to every connection of the pool
+	applicationName := conf.PostgresApplicationName
+	if applicationName == "" {
+		applicationName = conf.ServiceName
+	}
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = applicationName
+	if conf.PostgresStatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(conf.PostgresStatementTimeout.Milliseconds(), 10)
 	}
+	return poolConfig, nil
+}
 
-	// Set connection pool options
-	PostgresConn.Config().MaxConns = int32(config.Conf.PostgresMaxOpenConns)
-	PostgresConn.Config().MaxConnIdleTime = time.Duration(config.Conf.PostgresMaxIdleConns)
+// New connects to the database configured by conf and starts checking its
+// replicas. It returns a nil DB when the database capability is disabled; the
+// cleanup function closes every pool.
+func New(conf *config.Config, capabilities *capability.Registry) (*DB, func(), error) {
+	if !capabilities.Enabled(capability.Database) {
+		return nil, func() {}, nil
+	}
+	poolConfig, err := PoolConfig(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	primary, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening database connection: %w", err)
+	}
 
+	// Ping to check the connection
+	if err := primary.Ping(context.Background()); err != nil {
+		primary.Close()
+		return nil, nil, fmt.Errorf("error pinging database: %w", err)
+	}
 	log.Println("🎊 Connected to the database successfully")
-	return nil
+
+	d := &DB{conf: conf, primary: primary}
+	if err := d.openReplicas(); err != nil {
+		d.Close()
+		return nil, nil, err
+	}
+	return d, d.Close, nil
+}
+
+// Pool returns the primary pool, for queries outside request transactions
+func (d *DB) Pool() *pgxpool.Pool {
+	if d == nil {
+		return nil
+	}
+	return d.primary
 }
 
 // PGTransaction begins a new transaction with pgx.
-func PGTransaction(ctx context.Context) (pgx.Tx, error) {
-	tx, err := PostgresConn.Begin(ctx)
+func (d *DB) PGTransaction(ctx context.Context) (pgx.Tx, error) {
+	return d.PGTransactionWithOptions(ctx, pgx.TxOptions{})
+}
+
+// PGTransactionWithOptions begins a new transaction with the given isolation
+// level and access mode. Read-only transactions go to a replica when
+// POSTGRES_REPLICA_HOSTS is set, see ReadPool.
+func (d *DB) PGTransactionWithOptions(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	// New only returns a nil DB when the database capability is disabled
+	if d == nil {
+		return nil, &capability.DisabledError{Name: capability.Database, Reason: "DATABASE_ENABLED=false"}
+	}
+	if opts.AccessMode == pgx.ReadOnly && len(d.replicas) > 0 {
+		return d.beginReadTrx(ctx, opts)
+	}
+	tx, err := d.primary.BeginTx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +168,8 @@ func PGTransaction(ctx context.Context) (pgx.Tx, error) {
 	return tx, nil
 }
 
-// Close closes the database connection.
-func Close() {
-	PostgresConn.Close()
+// Close closes the database connection and the replica pools.
+func (d *DB) Close() {
+	d.closeReplicas()
+	d.primary.Close()
 }
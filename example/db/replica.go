@@ -0,0 +1,261 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Read replicas are listed in POSTGRES_REPLICA_HOSTS. Read-only transactions
+// (handler.ReadOnly, PGReadTransaction) and ReadPool queries go to the healthy
+// replicas in turn; writes and everything else stay on the primary. A replica
+// is ejected while it cannot be reached or lags more than
+// POSTGRES_REPLICA_MAX_LAG, and reads fall back to the primary when no replica
+// is left.
+
+// replicaLagQuery returns how far a replica is behind in seconds, 0 when it has
+// replayed everything it received or is not a replica at all
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8`
+
+// ReplicaStatus is the health of a replica as seen by the last check
+type ReplicaStatus struct {
+	Name    string
+	Healthy bool
+	Lag     time.Duration
+	Error   string
+}
+
+type replica struct {
+	name   string
+	pool   *pgxpool.Pool
+	maxLag time.Duration
+
+	mu     sync.RWMutex
+	status ReplicaStatus
+}
+
+// openReplicas opens a pool per replica host, checks them once and keeps
+// checking them in the background. Unreachable replicas do not fail startup,
+// they start ejected.
+func (d *DB) openReplicas() error {
+	for _, host := range d.conf.PostgresReplicaHosts {
+		name, port, err := replicaAddress(host, d.conf.PostgresPort)
+		if err != nil {
+			return err
+		}
+		// Parsed from the replica's own DSN, so sslmode=verify-full checks the
+		// replica's certificate against its host name, not the primary's
+		poolConfig, err := poolConfigFor(d.conf, postgresURL(d.conf, name, port))
+		if err != nil {
+			return err
+		}
+
+		pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+		if err != nil {
+			return fmt.Errorf("error opening replica %s: %w", host, err)
+		}
+		d.replicas = append(d.replicas, &replica{
+			name:   net.JoinHostPort(name, fmt.Sprint(port)),
+			pool:   pool,
+			maxLag: d.conf.PostgresReplicaMaxLag,
+		})
+	}
+	if len(d.replicas) == 0 {
+		return nil
+	}
+
+	d.checkReplicas(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	d.stopReplicas = cancel
+	d.replicasDone.Add(1)
+	go func() {
+		defer d.replicasDone.Done()
+		ticker := time.NewTicker(d.conf.PostgresReplicaCheckPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.checkReplicas(ctx)
+			}
+		}
+	}()
+
+	log.Printf("🎊 Routing reads to %d replicas", len(d.replicas))
+	return nil
+}
+
+// replicaAddress splits host:port, using the primary's port when there is none
+func replicaAddress(host, defaultPort string) (string, string, error) {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, defaultPort
+	}
+	var number uint16
+	if _, err := fmt.Sscan(port, &number); err != nil || name == "" {
+		return "", "", fmt.Errorf("invalid replica host %q in POSTGRES_REPLICA_HOSTS", host)
+	}
+	return name, port, nil
+}
+
+// checkReplicas measures the lag of every replica and ejects or restores them
+func (d *DB) checkReplicas(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range d.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			r.check(ctx)
+		}(r)
+	}
+	wg.Wait()
+}
+
+func (r *replica) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	status := ReplicaStatus{Name: r.name}
+	var seconds float64
+	if err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&seconds); err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Lag = time.Duration(seconds * float64(time.Second))
+		if r.maxLag > 0 && status.Lag > r.maxLag {
+			status.Error = fmt.Sprintf("lag %s exceeds %s", status.Lag.Round(time.Millisecond), r.maxLag)
+		} else {
+			status.Healthy = true
+		}
+	}
+	r.setStatus(status)
+}
+
+// eject marks the replica unhealthy until the next successful check
+func (r *replica) eject(err error) {
+	r.setStatus(ReplicaStatus{Name: r.name, Error: err.Error()})
+}
+
+func (r *replica) setStatus(status ReplicaStatus) {
+	r.mu.Lock()
+	was := r.status
+	r.status = status
+	r.mu.Unlock()
+
+	if was.Healthy && !status.Healthy {
+		log.Printf("⚠️ Replica %s ejected: %s", r.name, status.Error)
+	} else if !was.Healthy && status.Healthy && was.Error != "" {
+		log.Printf("✅ Replica %s is back", r.name)
+	} else if was.Name == "" && !status.Healthy {
+		log.Printf("⚠️ Replica %s is unavailable: %s", r.name, status.Error)
+	}
+}
+
+func (r *replica) healthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status.Healthy
+}
+
+// healthyReplicas returns the healthy replicas, starting with the next one in
+// round-robin order
+func (d *DB) healthyReplicas() []*replica {
+	if len(d.replicas) == 0 {
+		return nil
+	}
+	start := int(d.next.Add(1) % uint64(len(d.replicas)))
+	var healthy []*replica
+	for i := range d.replicas {
+		if r := d.replicas[(start+i)%len(d.replicas)]; r.healthy() {
+			healthy = append(healthy, r)
+		}
+	}
+	return healthy
+}
+
+// ReadPool returns the pool for a read-only query: the next healthy replica,
+// or the primary when there is none. Reads from a replica may not see writes
+// committed a moment ago; read those from Pool.
+func (d *DB) ReadPool() *pgxpool.Pool {
+	if d == nil {
+		return nil
+	}
+	if healthy := d.healthyReplicas(); len(healthy) > 0 {
+		return healthy[0].pool
+	}
+	return d.primary
+}
+
+// PGReadTransaction begins a read-only transaction on a replica, or on the
+// primary when no replica is healthy.
+func (d *DB) PGReadTransaction(ctx context.Context) (pgx.Tx, error) {
+	return d.PGTransactionWithOptions(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+}
+
+// beginReadTrx tries the healthy replicas in turn, ejecting those that fail,
+// and falls back to the primary
+func (d *DB) beginReadTrx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	for _, r := range d.healthyReplicas() {
+		tx, err := r.pool.BeginTx(ctx, opts)
+		if err == nil {
+			return tx, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		r.eject(err)
+	}
+	return d.primary.BeginTx(ctx, opts)
+}
+
+// Replicas returns the status of every replica
+func (d *DB) Replicas() []ReplicaStatus {
+	if d == nil {
+		return nil
+	}
+	statuses := make([]ReplicaStatus, 0, len(d.replicas))
+	for _, r := range d.replicas {
+		r.mu.RLock()
+		status := r.status
+		r.mu.RUnlock()
+		status.Name = r.name
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// closeReplicas stops the health checks and closes the replica pools
+func (d *DB) closeReplicas() {
+	if d.stopReplicas != nil {
+		d.stopReplicas()
+		d.replicasDone.Wait()
+	}
+	for _, r := range d.replicas {
+		r.pool.Close()
+	}
+	d.replicas = nil
+}
@@ -0,0 +1,46 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package di
+
+import (
+	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/capability"
+	"github.com/burapha44/example/config"
+	"github.com/burapha44/example/db"
+	"github.com/burapha44/example/session"
+
+	"github.com/google/wire"
+)
+
+// InfraSet provides the configuration, the capabilities it switches on, the
+// database, the Redis client, the cache store selected by CACHE_DRIVER, the
+// typed cache and the session manager to the providers of the app. Disabled
+// subsystems are provided as nil.
+var InfraSet = wire.NewSet(
+	startupConfig,
+	capability.New,
+	db.New,
+	cache.New,
+	cache.NewStore,
+	cache.NewCache,
+	session.NewSessionManager,
+)
+
+// startupConfig is the configuration the app started with. Components that
+// follow reloads take *config.Manager instead.
+func startupConfig(configs *config.Manager) *config.Config {
+	return configs.Current()
+}
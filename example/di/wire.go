@@ -22,21 +22,32 @@ import (
 	"github.com/burapha44/example/api/v1/controllers"
 	"github.com/burapha44/example/api/v1/middleware"
 	"github.com/burapha44/example/api/v1/services"
+	"github.com/burapha44/example/capability"
+	"github.com/burapha44/example/config"
+	"github.com/burapha44/example/db"
+	"github.com/burapha44/example/session"
 
 	"github.com/google/wire"
 )
 
-func NewAppContainer() (*AppContainer, error) {
+// NewAppContainer builds the app from its configuration. The cleanup function
+// closes the database and cache connections.
+func NewAppContainer(configs *config.Manager) (*AppContainer, func(), error) {
 	wire.Build(
+		InfraSet,
 		middleware.ProviderSet,
 		services.ProviderSet,
 		controllers.ProviderSet,
 		wire.Struct(new(AppContainer), "*"),
 	)
-	return &AppContainer{}, nil
+	return &AppContainer{}, nil, nil
 }
 
 type AppContainer struct {
+	Config            *config.Manager
+	Capabilities      *capability.Registry
+	DB                *db.DB                  // nil when the database is disabled
+	Sessions          *session.SessionManager // nil when sessions are disabled
 	AuthMiddleware    *middleware.BaseMiddleware
 	BaseController    *controllers.BaseController
 	ProductController *controllers.ProductController
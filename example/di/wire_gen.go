@@ -10,26 +10,72 @@ import (
 	"github.com/burapha44/example/api/v1/controllers"
 	"github.com/burapha44/example/api/v1/middleware"
 	"github.com/burapha44/example/api/v1/services"
+	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/capability"
+	"github.com/burapha44/example/config"
+	"github.com/burapha44/example/db"
+	"github.com/burapha44/example/session"
 )
 
 // Injectors from wire.go:
 
-func NewAppContainer() (*AppContainer, error) {
-	baseMiddleware := middleware.NewBaseMiddleware()
-	baseService := services.NewBaseService()
+// NewAppContainer builds the app from its configuration. The cleanup function
+// closes the database and cache connections.
+func NewAppContainer(configs *config.Manager) (*AppContainer, func(), error) {
+	configConfig := startupConfig(configs)
+	registry, err := capability.New(configConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbDB, cleanup, err := db.New(configConfig, registry)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, cleanup2, err := cache.New(configConfig, registry)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	store, err := cache.NewStore(client, configConfig)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	sessionManager := session.NewSessionManager(configConfig, registry, store)
+	cacheCache, err := cache.NewCache(store, configConfig)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	tokenVerifier := middleware.NewTokenVerifier(configs)
+	baseMiddleware := middleware.NewBaseMiddleware(configs, cacheCache, tokenVerifier)
+	baseService := services.NewBaseService(configConfig, registry, dbDB, store)
 	baseController := controllers.NewBaseController(baseService)
 	productController := controllers.NewProductController()
 	appContainer := &AppContainer{
+		Config:            configs,
+		Capabilities:      registry,
+		DB:                dbDB,
+		Sessions:          sessionManager,
 		AuthMiddleware:    baseMiddleware,
 		BaseController:    baseController,
 		ProductController: productController,
 	}
-	return appContainer, nil
+	return appContainer, func() {
+		cleanup2()
+		cleanup()
+	}, nil
 }
 
 // wire.go:
 
 type AppContainer struct {
+	Config            *config.Manager
+	Capabilities      *capability.Registry
+	DB                *db.DB                  // nil when the database is disabled
+	Sessions          *session.SessionManager // nil when sessions are disabled
 	AuthMiddleware    *middleware.BaseMiddleware
 	BaseController    *controllers.BaseController
 	ProductController *controllers.ProductController
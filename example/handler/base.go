@@ -16,9 +16,12 @@
 package handler
 
 import (
+	"errors"
+	"log"
+
 	"github.com/gofiber/fiber/v2"
 
-	"github.com/burapha44/example/config"
+	"github.com/burapha44/example/capability"
 	"github.com/burapha44/example/constants"
 	"github.com/burapha44/example/types"
 	"github.com/burapha44/example/utils/localized"
@@ -32,18 +35,28 @@ func ErrorHandler(ctx *fiber.Ctx, err error) error {
 }
 
 // BuildError constructs a JSON response with the given error message and status code.
-// It also rolls back any active database transaction associated with the context.
-// If no status code is provided, it defaults to 500 (Internal Server Error).
-// The original error, if present, is included in the response details.s
+// It also finishes the request transaction, if the request began one: rolled back when
+// rollback is true, committed otherwise (a failing commit turns the response into
+// ERR_UNABLE_TO_COMMIT_TRX). If no status code is provided, it defaults to 500
+// (Internal Server Error). Errors caused by a disabled subsystem (capability.DisabledError)
+// become ERR_FEATURE_DISABLED with status 503. The original error, if present, is included
+// in the response details, except for 500 responses: an error value there is logged instead,
+// since database errors carry SQL, constraint names and row data that clients must not see.
 func BuildError(ctx *fiber.Ctx, ErrorCode string, code int, originalErr interface{}, rollback bool) error {
-	// rollback transaction
-	if config.Conf.PostgresUser != "" {
-		if rollback {
-			rollbackCtxTrx(ctx)
-		} else {
-			commitCtxTrx(ctx)
+	markRetryableTrxError(ctx, originalErr)
+	if err, ok := originalErr.(error); ok {
+		var disabled *capability.DisabledError
+		if errors.As(err, &disabled) {
+			ErrorCode, code = constants.FeatureDisabledCode, fiber.StatusServiceUnavailable
 		}
 	}
+	if rollback {
+		rollbackCtxTrx(ctx)
+	} else if err := commitCtxTrx(ctx); err != nil {
+		// Serialization failures mostly surface at COMMIT; Retry must see them too
+		markRetryableTrxError(ctx, err)
+		ErrorCode, code, originalErr = constants.UnableToCommitTrxCode, fiber.StatusInternalServerError, err
+	}
 
 	if code == 0 {
 		code = fiber.StatusInternalServerError
@@ -51,7 +64,9 @@ func BuildError(ctx *fiber.Ctx, ErrorCode string, code int, originalErr interfac
 
 	var detail interface{}
 
-	if originalErr != nil {
+	if err, ok := originalErr.(error); ok && code == fiber.StatusInternalServerError {
+		log.Printf("❌ %s %s: %v", ctx.Method(), ctx.Path(), err)
+	} else if originalErr != nil {
 		detail = originalErr
 	}
 	lang, ok := ctx.Locals(constants.LanguageKey).(string)
@@ -65,16 +80,12 @@ func BuildError(ctx *fiber.Ctx, ErrorCode string, code int, originalErr interfac
 	})
 }
 
-// Success commits the active database transaction associated with the given Fiber context
-// and returns a JSON response with the provided data. If committing the transaction fails,
-// an error is returned.
+// Success commits the request transaction, if the request began one, and returns a JSON
+// response with the provided data. If committing the transaction fails, the response is
+// an ERR_UNABLE_TO_COMMIT_TRX error instead.
 func Success(ctx *fiber.Ctx, data interface{}) error {
-	if config.Conf.PostgresUser != "" {
-		err := commitCtxTrx(ctx)
-
-		if err != nil {
-			return err
-		}
+	if err := commitCtxTrx(ctx); err != nil {
+		return BuildError(ctx, constants.UnableToCommitTrxCode, fiber.StatusInternalServerError, err, true)
 	}
 
 	return ctx.JSON(types.Response{
@@ -0,0 +1,34 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package handler
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/burapha44/example/cache"
+)
+
+// InvalidateCacheTags invalidates tags of c once the request transaction
+// commits, so a rolled back change leaves the cache alone and a reader cannot
+// cache the old rows again between the invalidation and the commit. Without a
+// request transaction the tags are invalidated right away.
+func InvalidateCacheTags(ctx *fiber.Ctx, c *cache.Cache, tags ...string) {
+	AfterCommit(ctx, func(hookCtx context.Context) error {
+		return cache.InvalidateTags(hookCtx, c, tags...)
+	})
+}
@@ -0,0 +1,133 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package handler
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	trxRetryKey = "db_trx_retry_key"
+
+	// SQLSTATE codes worth re-running the whole unit of work for
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// RetryPolicy configures how Retry re-executes a handler whose request
+// transaction failed with a serialization failure or a deadlock.
+type RetryPolicy struct {
+	// Attempts is the total number of executions, the first one included (default 3).
+	Attempts int
+	// Backoff is the delay before the first retry, doubled for every following one (default 20ms).
+	Backoff time.Duration
+	// MaxBackoff caps the delay between attempts (default 1s).
+	MaxBackoff time.Duration
+	// NonIdempotent allows retrying POST and PATCH handlers. Only set it when
+	// everything the handler does outside the transaction is safe to repeat.
+	NonIdempotent bool
+}
+
+// DefaultRetryPolicy retries idempotent handlers up to 3 times in total
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 20 * time.Millisecond, MaxBackoff: time.Second}
+
+// Retry wraps h so that it is executed again, on a fresh request transaction,
+// when Postgres reports a serialization failure (40001) or a deadlock (40P01),
+// whether h returned the error or responded with it through BuildError.
+//
+// Only GET, HEAD, OPTIONS, PUT and DELETE requests are retried unless
+// policy.NonIdempotent is set; the route has to opt in by wrapping its handler:
+//
+//	app.Put("/products/:id", handler.Retry(handler.DefaultRetryPolicy, product.Update))
+func Retry(policy RetryPolicy, h fiber.Handler) fiber.Handler {
+	if policy.Attempts <= 0 {
+		policy.Attempts = DefaultRetryPolicy.Attempts
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = DefaultRetryPolicy.Backoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		if !policy.NonIdempotent && !isIdempotentMethod(ctx.Method()) {
+			return h(ctx)
+		}
+
+		backoff := policy.Backoff
+		for attempt := 1; ; attempt++ {
+			ctx.Locals(trxRetryKey, nil)
+			err := h(ctx)
+
+			cause := err
+			if cause == nil {
+				cause, _ = ctx.Locals(trxRetryKey).(error)
+			}
+			if !IsRetryableTrxError(cause) || attempt >= policy.Attempts {
+				return err
+			}
+
+			// discard the failed attempt: its transaction, hooks and response
+			rollbackCtxTrx(ctx)
+			ctx.Locals(DbTrxKey, nil)
+			ctx.Response().ResetBody()
+			ctx.Status(fiber.StatusOK)
+
+			delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+			log.Printf("🔁 Retrying %s %s after %v (attempt %d/%d): %v",
+				ctx.Method(), ctx.Path(), delay, attempt+1, policy.Attempts, cause)
+			select {
+			case <-time.After(delay):
+			case <-ctx.UserContext().Done():
+				return ctx.UserContext().Err()
+			}
+			backoff = min(backoff*2, policy.MaxBackoff)
+		}
+	}
+}
+
+// IsRetryableTrxError reports whether err is a Postgres serialization failure
+// or deadlock, after which the transaction can succeed if run again
+func IsRetryableTrxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
+
+// markRetryableTrxError remembers a retryable error answered by BuildError so
+// Retry can re-run the handler although it returned nil
+func markRetryableTrxError(ctx *fiber.Ctx, originalErr interface{}) {
+	if err, ok := originalErr.(error); ok && IsRetryableTrxError(err) {
+		ctx.Locals(trxRetryKey, err)
+	}
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodPut, fiber.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -16,6 +16,9 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
@@ -25,56 +28,193 @@ import (
 	"github.com/burapha44/example/db"
 )
 
-// rollbackCtxTrx rollbacks active database transaction associated with the given Fiber context.
-// If no transaction is associated with the context, it does nothing.
-func rollbackCtxTrx(ctx *fiber.Ctx) {
-	trx, _ := startNewPGTrx(ctx)
+// Request transactions are lazy and opt-in: nothing is begun until a service
+// calls GetTrx. The transaction is then finished exactly once, by Success
+// (commit), BuildError (rollback or commit) or, if the handler returned without
+// either, by the Transaction middleware.
+
+const (
+	DbKey    = "db_key"
+	DbTrxKey = "db_trx_key"
+)
+
+// TrxOption configures the request transaction before it begins
+type TrxOption func(*pgx.TxOptions)
+
+// Serializable runs the request transaction at the serializable isolation level
+func Serializable() TrxOption {
+	return Isolation(pgx.Serializable)
+}
+
+// ReadOnly makes the request transaction read-only, on a read replica when
+// POSTGRES_REPLICA_HOSTS is set
+func ReadOnly() TrxOption {
+	return func(opts *pgx.TxOptions) { opts.AccessMode = pgx.ReadOnly }
+}
+
+// Isolation runs the request transaction at the given isolation level
+func Isolation(level pgx.TxIsoLevel) TrxOption {
+	return func(opts *pgx.TxOptions) { opts.IsoLevel = level }
+}
+
+// AfterCommitHook runs once the request transaction has been committed
+type AfterCommitHook func(ctx context.Context) error
 
-	if trx != nil {
-		if err := trx.Rollback(ctx.UserContext()); err != nil {
-			log.Fatalf("Error rollback transaction: %v", err)
+// requestTrx is the transaction of a request and whether it was finished
+type requestTrx struct {
+	tx    pgx.Tx
+	opts  pgx.TxOptions
+	done  bool
+	hooks []AfterCommitHook
+}
+
+// GetTrx returns the Postgres transaction of the request, beginning it on first
+// use. Services call it so their queries join the request transaction.
+//
+// Options only apply when the call begins the transaction; asking for other
+// options once it has begun (e.g. Serializable after a default read) is an
+// error, so services needing them should call GetTrx first.
+func GetTrx(ctx *fiber.Ctx, options ...TrxOption) (pgx.Tx, error) {
+	var opts pgx.TxOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	if trx, ok := ctx.Locals(DbTrxKey).(*requestTrx); ok {
+		if trx.done {
+			return nil, errors.New("request transaction is already finished")
 		}
+		if len(options) > 0 && !sameTrxOptions(trx.opts, opts) {
+			return nil, fmt.Errorf("request transaction already began with isolation %q and access mode %q",
+				trx.opts.IsoLevel, trx.opts.AccessMode)
+		}
+		return trx.tx, nil
 	}
+
+	database, _ := ctx.Locals(DbKey).(*db.DB)
+	tx, err := database.PGTransactionWithOptions(ctx.UserContext(), opts)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Locals(DbTrxKey, &requestTrx{tx: tx, opts: opts})
+	return tx, nil
 }
 
-// commitCtxTrx commits active database transaction associated with the given Fiber context.
-// If no transaction is associated with the context, it does nothing.
-// If commit fails, it returns an error response to the client with status code 500 (Internal Server Error).
-func commitCtxTrx(ctx *fiber.Ctx) error {
-	trx, err := startNewPGTrx(ctx)
+func sameTrxOptions(a, b pgx.TxOptions) bool {
+	return a.IsoLevel == b.IsoLevel && a.AccessMode == b.AccessMode && a.DeferrableMode == b.DeferrableMode
+}
 
+// WithSavepoint runs fn in a nested unit of work of the request transaction,
+// beginning the transaction if needed. fn receives a savepoint: if fn returns
+// an error the work done inside it, including after-commit hooks registered
+// meanwhile, is rolled back while the outer transaction carries on.
+func WithSavepoint(ctx *fiber.Ctx, fn func(tx pgx.Tx) error) error {
+	tx, err := GetTrx(ctx)
 	if err != nil {
-		return BuildError(ctx, constants.UnableToGetTrxCode, fiber.StatusInternalServerError, err, true)
+		return err
 	}
+	trx := activeTrx(ctx)
+	hooks := len(trx.hooks)
 
-	if trx != nil {
-		if err := trx.Commit(ctx.UserContext()); err != nil {
-			return BuildError(ctx, constants.UnableToCommitTrxCode, fiber.StatusInternalServerError, err, true)
+	savepoint, err := tx.Begin(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+	if err := fn(savepoint); err != nil {
+		trx.hooks = trx.hooks[:hooks]
+		if rbErr := savepoint.Rollback(ctx.UserContext()); rbErr != nil {
+			return errors.Join(err, rbErr)
 		}
+		return err
 	}
-
-	return nil
+	return savepoint.Commit(ctx.UserContext())
 }
 
-const (
-	DbTrxKey = "db_trx_key"
-)
+// AfterCommit registers hook to run once the request transaction commits, for
+// work that must not happen if it rolls back (enqueueing messages, cache
+// invalidation). Hooks are dropped on rollback. Without an open request
+// transaction there is nothing to wait for and hook runs immediately.
+// Hook errors are logged, the commit has already succeeded.
+func AfterCommit(ctx *fiber.Ctx, hook AfterCommitHook) {
+	trx := activeTrx(ctx)
+	if trx == nil {
+		runAfterCommitHooks(ctx.UserContext(), []AfterCommitHook{hook})
+		return
+	}
+	trx.hooks = append(trx.hooks, hook)
+}
 
-// StartNewPGTrx returns a new Postgres transaction associated with the given Fiber context.
-// If a transaction is already associated with the context, it is returned instead of creating
-// a new one. The transaction is stored in the context under the key DbTrxKey.
-func startNewPGTrx(ctx *fiber.Ctx) (pgx.Tx, error) {
-	if trx := ctx.Locals(DbTrxKey); trx != nil {
-		return trx.(pgx.Tx), nil
+func runAfterCommitHooks(ctx context.Context, hooks []AfterCommitHook) {
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("⚠️ After-commit hook panicked: %v", r)
+				}
+			}()
+			if err := hook(ctx); err != nil {
+				log.Printf("⚠️ After-commit hook failed: %v", err)
+			}
+		}()
 	}
+}
 
-	pgTrx, err := db.PGTransaction(ctx.UserContext())
+// Transaction is a middleware giving GetTrx the database of the app and
+// finishing the request transaction when the handler did not: it is rolled
+// back if the handler returned an error and committed otherwise. A failing
+// commit replaces the response with an error. database is nil when the
+// database capability is disabled.
+func Transaction(database *db.DB) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		ctx.Locals(DbKey, database)
+		if err := ctx.Next(); err != nil {
+			rollbackCtxTrx(ctx)
+			return err
+		}
+		if err := commitCtxTrx(ctx); err != nil {
+			return BuildError(ctx, constants.UnableToCommitTrxCode, fiber.StatusInternalServerError, err, true)
+		}
+		return nil
+	}
+}
 
-	if err != nil {
-		return nil, err
+// activeTrx returns the unfinished transaction of the request, or nil
+func activeTrx(ctx *fiber.Ctx) *requestTrx {
+	trx, ok := ctx.Locals(DbTrxKey).(*requestTrx)
+	if !ok || trx.done {
+		return nil
 	}
+	return trx
+}
 
-	ctx.Locals(DbTrxKey, pgTrx)
+// rollbackCtxTrx rolls back the request transaction if one was begun and not
+// finished yet. Rollback errors are logged, the connection is released either way.
+func rollbackCtxTrx(ctx *fiber.Ctx) {
+	trx := activeTrx(ctx)
+	if trx == nil {
+		return
+	}
+	trx.done = true
+	trx.hooks = nil
+	if err := trx.tx.Rollback(ctx.UserContext()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		log.Printf("⚠️ Error rolling back transaction: %v", err)
+	}
+}
 
-	return pgTrx, nil
+// commitCtxTrx commits the request transaction if one was begun and not
+// finished yet, runs its after-commit hooks and returns the commit error so it
+// can become the response.
+func commitCtxTrx(ctx *fiber.Ctx) error {
+	trx := activeTrx(ctx)
+	if trx == nil {
+		return nil
+	}
+	trx.done = true
+	hooks := trx.hooks
+	trx.hooks = nil
+	if err := trx.tx.Commit(ctx.UserContext()); err != nil {
+		return err
+	}
+	runAfterCommitHooks(ctx.UserContext(), hooks)
+	return nil
 }
@@ -16,23 +16,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
-	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 
 	"github.com/fatih/color"
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
+	fiberlog "github.com/gofiber/fiber/v2/log"
 
-	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/capability"
 	"github.com/burapha44/example/cmd"
 	"github.com/burapha44/example/config"
-	"github.com/burapha44/example/db"
+	"github.com/burapha44/example/di"
 	"github.com/burapha44/example/shared"
 	"github.com/burapha44/example/utils"
 	"github.com/burapha44/example/utils/localized"
@@ -108,58 +109,54 @@ func init() {
 }
 
 func main() {
-	env := os.Getenv("ENV")
-	fileEnv := "./.env.local"
-
-	if env == "" || env == "dev" {
-		env = "dev"
-		fileEnv = "./.env.local"
-	}
-
-	if env == "prod" {
-		fileEnv = "./.env"
-	}
-
-	if godotenv.Load(fileEnv) != nil {
-		log.Fatal("Error loading .env file")
-	}
-	confVars, configErr := config.New()
+	// config.yaml, .env.<ENV>, environment variables and flags, all optional
+	configs, configErr := config.NewManager(os.Args[1:]...)
 	if configErr != nil {
 		log.Fatal(configErr)
 	}
+	confVars := configs.Current()
 
 	if err := localized.LoadLanguage("lang"); err != nil {
 		log.Fatal(err)
 	}
-	localized.SetDefaultLanguage(localized.DefaultLanguage)
-
-	if confVars.PostgresUser != "" {
-		dbErr := db.Init()
-
-		if dbErr != nil {
-			// log.Fatal(dbErr)
-			fmt.Println("❌ ไม่สามารถเชื่อมต่อกับฐานข้อมูลได้")
-			os.Exit(1)
+	localized.SetDefaultLanguage(confVars.DefaultLanguage)
+	fiberlog.SetLevel(logLevel(confVars.LogLevel))
+
+	// Reload config.yaml and the env files when they change or on SIGHUP.
+	// CORS and rate limits subscribe in cmd.InitApp and the middleware.
+	configs.Subscribe(func(old, new *config.Config) {
+		if err := localized.LoadLanguage("lang"); err != nil {
+			log.Printf("❌ Cannot reload languages: %v", err)
 		}
-
-		defer db.Close()
-	}
-
-	if confVars.RedisHost != "" {
-		cacheErr := cache.Init()
-
-		if cacheErr != nil {
-			log.Fatal(cacheErr)
+		localized.SetDefaultLanguage(new.DefaultLanguage)
+		fiberlog.SetLevel(logLevel(new.LogLevel))
+	})
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go func() {
+		if err := configs.Watch(watchCtx); err != nil {
+			log.Printf("⚠️ Configuration hot reload disabled: %v", err)
 		}
+	}()
 
-		defer cache.Close()
+	// DATABASE_ENABLED, CACHE_DRIVER, SESSION_ENABLED and DOCS_ENABLED switch
+	// subsystems on. Connects the database and Redis when enabled, see di.InfraSet
+	container, cleanup, err := di.NewAppContainer(configs)
+	if err != nil {
+		fmt.Println("❌ Failed to initialize DI Container:", err)
+		os.Exit(1)
 	}
+	defer cleanup()
+	log.Println("✅ DI Container initialized successfully")
 
-	app := cmd.InitApp()
-	docsApp := cmd.InitDocsApp()
+	app := cmd.InitApp(container)
+	var docsApp *fiber.App
+	if container.Capabilities.Enabled(capability.Docs) {
+		docsApp = cmd.InitDocsApp()
+	}
 
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(1)
 
 	// Graceful shutdown signal handler
 	shutdownChan := make(chan os.Signal, 1)
@@ -174,13 +171,16 @@ func main() {
 		}
 	}()
 
-	go func() {
-		defer wg.Done()
-		docsPort := "8081"
-		if err := docsApp.Listen(":" + docsPort); err != nil {
-			log.Printf("Docs server stopped: %v", err)
-		}
-	}()
+	if docsApp != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			docsPort := "8081"
+			if err := docsApp.Listen(":" + docsPort); err != nil {
+				log.Printf("Docs server stopped: %v", err)
+			}
+		}()
+	}
 
 	// Wait for OS signal to gracefully shutdown both servers
 	go func() {
@@ -191,25 +191,35 @@ func main() {
 			log.Printf("Failed to shutdown app server: %v", err)
 		}
 
-		if err := docsApp.Shutdown(); err != nil {
-			log.Printf("Failed to shutdown docs server: %v", err)
+		if docsApp != nil {
+			if err := docsApp.Shutdown(); err != nil {
+				log.Printf("Failed to shutdown docs server: %v", err)
+			}
 		}
 	}()
 
-	utils.ShowBanner(
-		color.RGB(102, 178, 255).Sprintf("\033[1mNVS Structure + Fiber v2\033[0m"),
+	banner := []string{
 		color.RGB(102, 178, 255).Sprintf("© 2025 Nevilsoft Ltd., Part."),
 		"",
 		formatLine(color.BlackString("🔗 API Server Info: ")+color.CyanString("\033[4mhttp://"+ip+confVars.Port+"/api/v1/server/info\033[0m"), "start"),
-		formatLine(color.BlackString("🔗 API Docs: ")+color.CyanString("\033[4mhttp://"+ip+":8081"+"/reference\033[0m"), "start"),
-		"",
-		formatLine(color.BlackString("Set Config In: ")+color.CyanString(".env.local"), "start"),
-		formatLine(color.BlackString("Database Enabled: ")+color.CyanString(strconv.FormatBool(config.Conf.DatabaseEnabled)), "start"),
-		formatLine(color.BlackString("Redis Enabled: ")+color.CyanString(strconv.FormatBool(config.Conf.RedisHost != "")), "start"),
-		formatLine(color.BlackString("Environment: ")+color.CyanString(config.Conf.Environment), "start"),
-		formatLine(color.BlackString("Origin: ")+color.CyanString(config.Conf.AllowOrigins), "start"),
+	}
+	if docsApp != nil {
+		banner = append(banner, formatLine(color.BlackString("🔗 API Docs: ")+color.CyanString("\033[4mhttp://"+ip+":8081"+"/reference\033[0m"), "start"))
+	}
+	banner = append(banner, "", formatLine(color.BlackString("Set Config In: ")+color.CyanString(configFiles(configs)), "start"))
+	for _, capa := range container.Capabilities.All() {
+		state := "disabled"
+		if capa.Enabled {
+			state = "enabled"
+		}
+		banner = append(banner, formatLine(color.BlackString(strings.ToUpper(string(capa.Name[:1]))+string(capa.Name[1:])+": ")+color.CyanString(state), "start"))
+	}
+	banner = append(banner,
+		formatLine(color.BlackString("Environment: ")+color.CyanString(confVars.Environment), "start"),
+		formatLine(color.BlackString("Origin: ")+color.CyanString(confVars.AllowOrigins), "start"),
 		formatLine(color.BlackString("Version: ")+color.CyanString(Version), "start"),
 	)
+	utils.ShowBanner(color.RGB(102, 178, 255).Sprintf("\033[1mNVS Structure + Fiber v2\033[0m"), banner...)
 	color.Cyan("\n🚀 Server is running... Press Ctrl+C to stop")
 
 	wg.Wait()
@@ -257,6 +267,29 @@ func startServer(a *fiber.App, fiberConnURL string) {
 	}
 }
 
+// logLevel converts LOG_LEVEL to the level of the Fiber logger
+func logLevel(name string) fiberlog.Level {
+	switch name {
+	case "trace":
+		return fiberlog.LevelTrace
+	case "debug":
+		return fiberlog.LevelDebug
+	case "warn":
+		return fiberlog.LevelWarn
+	case "error":
+		return fiberlog.LevelError
+	}
+	return fiberlog.LevelInfo
+}
+
+// configFiles lists the configuration files that were loaded
+func configFiles(configs *config.Manager) string {
+	if files := configs.Sources().Files(); len(files) > 0 {
+		return strings.Join(files, ", ")
+	}
+	return "environment variables"
+}
+
 func formatLine(text, align string) string {
 	return fmt.Sprintf("%s|%s", align, text)
 }
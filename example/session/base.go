@@ -18,30 +18,36 @@ package session
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
-	"github.com/gofiber/storage/redis"
 
+	"github.com/burapha44/example/cache"
+	"github.com/burapha44/example/capability"
 	"github.com/burapha44/example/config"
+	"github.com/burapha44/example/constants"
 )
 
 type SessionManager struct {
 	Store *session.Store
 }
 
-func NewSessionManager() *SessionManager {
-	storage := redis.New(redis.Config{
-		Host:     config.Conf.RedisHost,
-		Port:     config.Conf.RedisPort,
-		Password: config.Conf.RedisPassword,
-		Database: 2,
-		Reset:    false,
-	})
+// NewSessionManager stores sessions in store, the cache store selected by
+// CACHE_DRIVER, under "<SERVICE_NAME>:session:". With CACHE_DRIVER=memory
+// sessions get a MemoryStore of their own, so a burst of cache entries cannot
+// evict them and log users out. It returns nil when the session capability is
+// disabled.
+func NewSessionManager(conf *config.Config, capabilities *capability.Registry, store cache.Store) *SessionManager {
+	if !capabilities.Enabled(capability.Session) || store == nil {
+		return nil
+	}
+	if _, ok := store.(*cache.MemoryStore); ok {
+		store = cache.NewMemoryStore(conf.CacheMemoryMaxEntries)
+	}
 
-	store := session.New(session.Config{
-		Storage:   storage,
-		KeyLookup: "cookie:sid",
+	sessionStore := session.New(session.Config{
+		Storage:   &storage{store: store, prefix: conf.ServiceName + ":session:"},
+		KeyLookup: "cookie:" + constants.SessionCookie,
 	})
 
-	return &SessionManager{Store: store}
+	return &SessionManager{Store: sessionStore}
 }
 
 func (sm *SessionManager) Middleware() fiber.Handler {
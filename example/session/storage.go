@@ -0,0 +1,57 @@
+/*
+ * Created on Tue Mar 04 2025
+ *
+ * © 2025 Nevilsoft Ltd., Part. All Rights Reserved.
+ *
+ * * ข้อมูลลับและสงวนสิทธิ์ *
+ * ไฟล์นี้เป็นทรัพย์สินของ Nevilsoft Ltd., Part. และมีข้อมูลที่เป็นความลับทางธุรกิจ
+ * อนุญาตให้เฉพาะพนักงานที่ได้รับสิทธิ์เข้าถึงเท่านั้น
+ * ห้ามเผยแพร่ คัดลอก ดัดแปลง หรือใช้งานโดยไม่ได้รับอนุญาตจากฝ่ายบริหาร
+ *
+ * การละเมิดข้อตกลงนี้ อาจมีผลให้ถูกลงโทษทางวินัย รวมถึงการดำเนินคดีตามกฎหมาย
+ * ตามพระราชบัญญัติว่าด้วยการกระทำความผิดเกี่ยวกับคอมพิวเตอร์ พ.ศ. 2560 (มาตรา 7, 9, 10)
+ * และกฎหมายอื่นที่เกี่ยวข้อง
+ */
+
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/burapha44/example/cache"
+)
+
+// storage adapts a cache.Store to fiber.Storage, which the session middleware
+// keeps its sessions in
+type storage struct {
+	store  cache.Store
+	prefix string
+}
+
+func (s *storage) Get(key string) ([]byte, error) {
+	value, _, err := s.store.Get(context.Background(), s.prefix+key)
+	return value, err
+}
+
+func (s *storage) Set(key string, value []byte, exp time.Duration) error {
+	if key == "" || len(value) == 0 {
+		return nil
+	}
+	return s.store.Set(context.Background(), s.prefix+key, value, exp)
+}
+
+func (s *storage) Delete(key string) error {
+	return s.store.Delete(context.Background(), s.prefix+key)
+}
+
+// Reset would drop the sessions of every instance, the store cannot list them
+func (s *storage) Reset() error {
+	return errors.New("resetting the session storage is not supported")
+}
+
+// Close leaves the store open, it is shared with the cache
+func (s *storage) Close() error {
+	return nil
+}
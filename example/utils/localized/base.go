@@ -19,29 +19,39 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 )
 
+// Language maps language codes to their messages. LoadLanguage replaces it
+// as a whole, so read it through Has and Msg when languages may be reloaded.
 var Language = make(map[string]map[string]string)
 var DefaultLanguage = "en"
 var FallbackLanguages = []string{"en"} // เพิ่มการตั้งค่าภาษา fallback ตามลำดับที่ต้องการ
 
+// mu guards Language, DefaultLanguage and FallbackLanguages
+var mu sync.RWMutex
+
 // LoadLanguage reads JSON files from the specified directory and loads them
 // into a global language map. It also allows loading additional fallback languages.
+// The loaded languages replace the previous ones only when every file is valid.
 func LoadLanguage(dir string) error {
 	files, err := os.ReadDir(dir)
 	if err != nil {
 		return err
 	}
+	loaded := make(map[string]map[string]string)
 	for _, file := range files {
 		if file.IsDir() {
 			continue
 		}
 
-		if ext := file.Name()[len(file.Name())-5:]; ext == ".json" {
-			lang := file.Name()[:len(file.Name())-5]
-			content, err := os.ReadFile(fmt.Sprintf("%s/%s", dir, file.Name()))
+		if ext := filepath.Ext(file.Name()); ext == ".json" {
+			lang := strings.TrimSuffix(file.Name(), ext)
+			content, err := os.ReadFile(filepath.Join(dir, file.Name()))
 			if err != nil {
 				return fmt.Errorf("error reading file %s: %w", file.Name(), err)
 			}
@@ -50,18 +60,33 @@ func LoadLanguage(dir string) error {
 			if err = json.Unmarshal(content, &messages); err != nil {
 				return fmt.Errorf("error un marshalling file %s: %w", file.Name(), err)
 			}
-			Language[lang] = messages
+			loaded[lang] = messages
 		}
 	}
+
+	mu.Lock()
+	Language = loaded
+	mu.Unlock()
 	color.RGB(102, 178, 255).Println("🌎 Loading languages successfully")
 
 	return nil
 }
 
+// Has reports whether messages for lang are loaded
+func Has(lang string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, exists := Language[lang]
+	return exists
+}
+
 // LocalizedMsg retrieves the localized message for a given language and message key.
 // It looks up the message key in the language map under the given language code.
 // It also allows falling back to a sequence of other languages before using the default.
 func Msg(lang string, msg string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
 	// ตรวจสอบภาษาที่ตรงกับที่เลือก
 	if localizedMsg, exists := Language[lang][msg]; exists {
 		return localizedMsg
@@ -85,10 +110,14 @@ func Msg(lang string, msg string) string {
 
 // SetFallbackLanguages allows setting custom fallback languages.
 func SetFallbackLanguages(langs []string) {
+	mu.Lock()
+	defer mu.Unlock()
 	FallbackLanguages = langs
 }
 
 // SetDefaultLanguage allows setting a custom default language.
 func SetDefaultLanguage(lang string) {
+	mu.Lock()
+	defer mu.Unlock()
 	DefaultLanguage = lang
 }